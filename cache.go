@@ -2,9 +2,21 @@ package cache
 
 import "time"
 
+// TTLInfo cache item ttl status
+type TTLInfo struct {
+	// Remaining time until item expire, zero for missing items and items without expiration
+	Remaining time.Duration
+	// HasExpiry is false for missing items and items without expiration
+	HasExpiry bool
+	// Exists is false for missing or expired items
+	Exists bool
+}
+
 // Cache interface for cache drivers.
 type Cache interface {
 	// Put a new value to cache
+	// ttl has millisecond precision, zero or negative ttl rejected and return false
+	// use PutForever for items with no expiry
 	Put(key string, value interface{}, ttl time.Duration) bool
 	// PutForever put value with infinite ttl
 	PutForever(key string, value interface{}) bool
@@ -18,8 +30,19 @@ type Cache interface {
 	Exists(key string) bool
 	// Forget item from cache (delete item)
 	Forget(key string) bool
-	// TTL get cache item ttl
+	// TTL get cache item remaining ttl with millisecond precision
+	// return zero for missing items and items without expiration
 	TTL(key string) time.Duration
+	// TTLInfo get cache item ttl status
+	TTLInfo(key string) TTLInfo
+	// Touch change cache item ttl without changing value
+	// zero or negative ttl rejected and return false
+	Touch(key string, ttl time.Duration) bool
+	// ExpireAt change cache item expiration time without changing value
+	// past time rejected and return false
+	ExpireAt(key string, at time.Time) bool
+	// Persist remove cache item expiration (infinite ttl)
+	Persist(key string) bool
 	// Bool parse dependency as boolean
 	Bool(key string, fallback bool) bool
 	// Int parse dependency as int
@@ -14,7 +14,7 @@ import (
 )
 
 type cacheRecord struct {
-	TTL  time.Time
+	TTL  time.Time // zero time for records without expiration
 	Data interface{}
 }
 
@@ -47,7 +47,7 @@ func (r *cacheRecord) Deserialize(data string) bool {
 
 // IsExpired check if record is expired
 func (r *cacheRecord) IsExpired() bool {
-	return r.TTL.UTC().Before(time.Now().UTC())
+	return !r.TTL.IsZero() && r.TTL.UTC().Before(time.Now().UTC())
 }
 
 // ParseAsInt64 parse data as int64
@@ -164,7 +164,7 @@ func (c *fileCache) pathResolver(key string) string {
 	return fileName
 }
 
-func (c *fileCache) read(key string) (*cacheRecord, bool) {
+func (c *fileCache) readRecord(key string) (*cacheRecord, bool) {
 	bytes, err := ioutil.ReadFile(c.pathResolver(key))
 	if err != nil {
 		return nil, false
@@ -173,25 +173,72 @@ func (c *fileCache) read(key string) (*cacheRecord, bool) {
 	if !rec.Deserialize(string(bytes)) {
 		return nil, false
 	}
+	return &rec, true
+}
+
+func (c *fileCache) read(key string) (*cacheRecord, bool) {
+	rec, exists := c.readRecord(key)
+	if !exists {
+		return nil, false
+	}
 
 	if rec.IsExpired() {
-		c.delete(key)
+		// check again under claim so item written after read not removed
+		c.claim(key, func() {
+			if rec, exists := c.readRecord(key); exists && rec.IsExpired() {
+				c.delete(key)
+			}
+		})
 		return nil, false
 	}
 
-	return &rec, true
+	return rec, true
 }
 
+// write write record to temp file and rename so item file never partially written
 func (c *fileCache) write(key string, record cacheRecord) bool {
-	utils.CreateDirectory(c.dir)
 	encoded, ok := record.Serialize()
-	if ok {
-		err := ioutil.WriteFile(c.pathResolver(key), []byte(encoded), 0644)
-		if err == nil {
-			return true
-		}
+	if !ok {
+		return false
+	}
+	token, err := randomToken()
+	if err != nil {
+		return false
+	}
+	temp := c.pathResolver(key) + "." + token
+	if ioutil.WriteFile(temp, []byte(encoded), 0644) != nil || os.Rename(temp, c.pathResolver(key)) != nil {
+		os.Remove(temp)
+		return false
 	}
-	return false
+	return true
+}
+
+// claim run action while holding claim file of item
+// all item changes run under claim so read-modify-write not undo concurrent changes
+func (c *fileCache) claim(key string, action func()) bool {
+	utils.CreateDirectory(c.dir)
+	return claimLock(c.pathResolver(key), action)
+}
+
+// update change existing item under claim
+func (c *fileCache) update(key string, change func(rec *cacheRecord) bool) bool {
+	updated := false
+	c.claim(key, func() {
+		rec, exists := c.readRecord(key)
+		if exists && !rec.IsExpired() && change(rec) {
+			updated = c.write(key, *rec)
+		}
+	})
+	return updated
+}
+
+// store write record under claim
+func (c *fileCache) store(key string, record cacheRecord) bool {
+	stored := false
+	c.claim(key, func() {
+		stored = c.write(key, record)
+	})
+	return stored
 }
 
 func (c *fileCache) delete(key string) bool {
@@ -200,30 +247,30 @@ func (c *fileCache) delete(key string) bool {
 
 // Put a new value to cache
 func (c *fileCache) Put(key string, value interface{}, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
 	record := cacheRecord{
 		TTL:  time.Now().UTC().Add(ttl),
 		Data: value,
 	}
-	return c.write(key, record)
+	return c.store(key, record)
 }
 
 // PutForever put value with infinite ttl
 func (c *fileCache) PutForever(key string, value interface{}) bool {
 	record := cacheRecord{
-		TTL:  time.Now().UTC().AddDate(100, 0, 0),
 		Data: value,
 	}
-	return c.write(key, record)
+	return c.store(key, record)
 }
 
 // Set Change value of cache item
 func (c *fileCache) Set(key string, value interface{}) bool {
-	rec, exists := c.read(key)
-	if exists {
+	return c.update(key, func(rec *cacheRecord) bool {
 		rec.Data = value
-		return c.write(key, *rec)
-	}
-	return false
+		return true
+	})
 }
 
 // Get item from cache
@@ -236,13 +283,16 @@ func (c *fileCache) Get(key string) interface{} {
 }
 
 // Pull item from cache and remove it
+// item read and removed under claim so only one concurrent pull get item
 func (c *fileCache) Pull(key string) interface{} {
-	defer c.delete(key)
-	rec, exists := c.read(key)
-	if exists {
-		return rec.Data
-	}
-	return nil
+	var value interface{}
+	c.claim(key, func() {
+		rec, exists := c.readRecord(key)
+		if c.delete(key) && exists && !rec.IsExpired() {
+			value = rec.Data
+		}
+	})
+	return value
 }
 
 // Check if item exists in cache
@@ -253,16 +303,64 @@ func (c *fileCache) Exists(key string) bool {
 
 // Forget item from cache (delete item)
 func (c *fileCache) Forget(key string) bool {
-	return c.delete(key)
+	deleted := false
+	c.claim(key, func() {
+		deleted = c.delete(key)
+	})
+	return deleted
 }
 
 // TTL get cache item ttl
 func (c *fileCache) TTL(key string) time.Duration {
+	return c.TTLInfo(key).Remaining
+}
+
+// TTLInfo get cache item ttl status
+func (c *fileCache) TTLInfo(key string) TTLInfo {
 	rec, exists := c.read(key)
-	if exists {
-		return time.Now().UTC().Sub(rec.TTL.UTC())
+	if !exists {
+		return TTLInfo{}
+	}
+	if rec.TTL.IsZero() {
+		return TTLInfo{Exists: true}
 	}
-	return 0
+
+	remaining := rec.TTL.UTC().Sub(time.Now().UTC())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return TTLInfo{
+		Remaining: remaining,
+		HasExpiry: true,
+		Exists:    true,
+	}
+}
+
+// Touch change cache item ttl without changing value
+func (c *fileCache) Touch(key string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	return c.ExpireAt(key, time.Now().UTC().Add(ttl))
+}
+
+// ExpireAt change cache item expiration time without changing value
+func (c *fileCache) ExpireAt(key string, at time.Time) bool {
+	if !at.After(time.Now()) {
+		return false
+	}
+	return c.update(key, func(rec *cacheRecord) bool {
+		rec.TTL = at.UTC()
+		return true
+	})
+}
+
+// Persist remove cache item expiration (infinite ttl)
+func (c *fileCache) Persist(key string) bool {
+	return c.update(key, func(rec *cacheRecord) bool {
+		rec.TTL = time.Time{}
+		return true
+	})
 }
 
 // Bool parse dependency as boolean
@@ -432,54 +530,58 @@ func (c *fileCache) Bytes(key string, fallback []byte) []byte {
 
 // Increment numeric item in cache
 func (c *fileCache) Increment(key string) bool {
-	rec, exists := c.read(key)
-	if exists {
-		if res, ok := rec.ParseAsFloat64(); ok {
-			return c.Set(key, res+1)
+	return c.update(key, func(rec *cacheRecord) bool {
+		res, ok := rec.ParseAsFloat64()
+		if ok {
+			rec.Data = res + 1
 		}
-	}
-	return false
+		return ok
+	})
 }
 
 // IncrementBy numeric item in cache by number
 func (c *fileCache) IncrementBy(key string, value interface{}) bool {
-	rec, exists := c.read(key)
-	if exists {
-		if res, ok := rec.ParseAsFloat64(); ok {
-			temp := cacheRecord{
-				Data: value,
-			}
-			if val, ok := temp.ParseAsFloat64(); ok {
-				return c.Set(key, res+val)
-			}
-		}
+	temp := cacheRecord{
+		Data: value,
+	}
+	val, ok := temp.ParseAsFloat64()
+	if !ok {
+		return false
 	}
-	return false
+	return c.update(key, func(rec *cacheRecord) bool {
+		res, ok := rec.ParseAsFloat64()
+		if ok {
+			rec.Data = res + val
+		}
+		return ok
+	})
 }
 
 // Decrement numeric item in cache
 func (c *fileCache) Decrement(key string) bool {
-	rec, exists := c.read(key)
-	if exists {
-		if res, ok := rec.ParseAsFloat64(); ok {
-			return c.Set(key, res-1)
+	return c.update(key, func(rec *cacheRecord) bool {
+		res, ok := rec.ParseAsFloat64()
+		if ok {
+			rec.Data = res - 1
 		}
-	}
-	return false
+		return ok
+	})
 }
 
 // DecrementBy numeric item in cache by number
 func (c *fileCache) DecrementBy(key string, value interface{}) bool {
-	rec, exists := c.read(key)
-	if exists {
-		if res, ok := rec.ParseAsFloat64(); ok {
-			temp := cacheRecord{
-				Data: value,
-			}
-			if val, ok := temp.ParseAsFloat64(); ok {
-				return c.Set(key, res-val)
-			}
-		}
+	temp := cacheRecord{
+		Data: value,
 	}
-	return false
+	val, ok := temp.ParseAsFloat64()
+	if !ok {
+		return false
+	}
+	return c.update(key, func(rec *cacheRecord) bool {
+		res, ok := rec.ParseAsFloat64()
+		if ok {
+			rec.Data = res - val
+		}
+		return ok
+	})
 }
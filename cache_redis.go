@@ -27,6 +27,36 @@ func (c *redisCache) init(prefix string, host string, maxIdle int, maxActive int
 	}
 }
 
+var redisCompareAndSwapScript = redis.NewScript(-1, `
+local current = redis.call("GET", KEYS[1])
+if (current == false and ARGV[1] == "0") or current == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0`)
+
+var redisIncrementWithinScript = redis.NewScript(-1, `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+var redisDecrementExistingScript = redis.NewScript(-1, `
+local count = tonumber(redis.call("GET", KEYS[1]))
+if count == nil or count <= 0 then
+	return 0
+end
+redis.call("DECR", KEYS[1])
+return 1`)
+
+var redisPullScript = redis.NewScript(-1, `
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value`)
+
 func (c *redisCache) client() redis.Conn {
 	return c.pool.Get()
 }
@@ -38,9 +68,53 @@ func (c *redisCache) prefixer(key string) string {
 	return c.prefix + "-" + key
 }
 
+// milliseconds convert duration to milliseconds, sub millisecond values rounded up
+func milliseconds(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// unixMilli get unix timestamp in milliseconds
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// eval run script with prefixed keys, script must created with -1 key count
+func (c *redisCache) eval(script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	conn := c.client()
+	defer conn.Close()
+	params := make([]interface{}, 0, len(keys)+len(args)+1)
+	params = append(params, len(keys))
+	for _, key := range keys {
+		params = append(params, c.prefixer(key))
+	}
+	params = append(params, args...)
+	return script.Do(conn, params...)
+}
+
+// incrementWithin atomically increment counter, expiration set when counter created
+func (c *redisCache) incrementWithin(key string, window time.Duration) (int64, bool) {
+	count, err := redis.Int64(c.eval(redisIncrementWithinScript, []string{key}, milliseconds(window)))
+	return count, err == nil
+}
+
+// decrementExisting atomically decrement counter if exists and positive
+func (c *redisCache) decrementExisting(key string) bool {
+	reply, err := redis.Int(c.eval(redisDecrementExistingScript, []string{key}))
+	return err == nil && reply == 1
+}
+
+// compareAndSwap atomically replace item if current value equals old, zero old means item not exists
+func (c *redisCache) compareAndSwap(key string, old int64, new int64, ttl time.Duration) bool {
+	reply, err := redis.Int(c.eval(redisCompareAndSwapScript, []string{key}, old, new, milliseconds(ttl)))
+	return err == nil && reply == 1
+}
+
 // Put a new value to cache
 func (c *redisCache) Put(key string, value interface{}, ttl time.Duration) bool {
-	if _, err := c.client().Do("SET", c.prefixer(key), value, "EX", int64(ttl/time.Second)); err == nil {
+	if ttl <= 0 {
+		return false
+	}
+	if _, err := c.client().Do("SET", c.prefixer(key), value, "PX", milliseconds(ttl)); err == nil {
 		return true
 	}
 	return false
@@ -70,12 +144,10 @@ func (c *redisCache) Get(key string) interface{} {
 	return nil
 }
 
-// Pull item from cache and remove it
+// Pull item from cache and remove it atomically
 func (c *redisCache) Pull(key string) interface{} {
-	if value, err := c.client().Do("GET", c.prefixer(key)); err == nil {
-		if _, err = c.client().Do("DEL", c.prefixer(key)); err == nil {
-			return value
-		}
+	if value, err := c.eval(redisPullScript, []string{key}); err == nil {
+		return value
 	}
 	return nil
 }
@@ -96,12 +168,60 @@ func (c *redisCache) Forget(key string) bool {
 
 // TTL get cache item ttl
 func (c *redisCache) TTL(key string) time.Duration {
-	ttl, err := redis.Int(c.client().Do("TTL", c.prefixer(key)))
-	if err != nil || ttl == -1 || ttl == -2 {
-		return 0
+	return c.TTLInfo(key).Remaining
+}
+
+// TTLInfo get cache item ttl status
+func (c *redisCache) TTLInfo(key string) TTLInfo {
+	conn := c.client()
+	defer conn.Close()
+	ttl, err := redis.Int64(conn.Do("PTTL", c.prefixer(key)))
+	if err != nil || ttl == -2 {
+		return TTLInfo{}
 	}
+	if ttl == -1 {
+		return TTLInfo{Exists: true}
+	}
+
+	return TTLInfo{
+		Remaining: time.Duration(ttl) * time.Millisecond,
+		HasExpiry: true,
+		Exists:    true,
+	}
+}
 
-	return time.Duration(ttl) * time.Second
+// Touch change cache item ttl without changing value
+func (c *redisCache) Touch(key string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	conn := c.client()
+	defer conn.Close()
+	reply, err := redis.Int(conn.Do("PEXPIRE", c.prefixer(key), milliseconds(ttl)))
+	return err == nil && reply == 1
+}
+
+// ExpireAt change cache item expiration time without changing value
+func (c *redisCache) ExpireAt(key string, at time.Time) bool {
+	if !at.After(time.Now()) {
+		return false
+	}
+	conn := c.client()
+	defer conn.Close()
+	reply, err := redis.Int(conn.Do("PEXPIREAT", c.prefixer(key), unixMilli(at)))
+	return err == nil && reply == 1
+}
+
+// Persist remove cache item expiration (infinite ttl)
+func (c *redisCache) Persist(key string) bool {
+	conn := c.client()
+	defer conn.Close()
+	reply, err := redis.Int(conn.Do("PERSIST", c.prefixer(key)))
+	if err != nil {
+		return false
+	}
+	// PERSIST return 0 for items without expiration too
+	return reply == 1 || c.Exists(key)
 }
 
 // Bool parse dependency as boolean
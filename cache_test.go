@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCaches get cache drivers to test
+// redis driver tested only if CACHE_TEST_REDIS set to redis host (e.g. 127.0.0.1:6379)
+func testCaches(t *testing.T) map[string]Cache {
+	caches := map[string]Cache{
+		"file": NewFileCache("test", t.TempDir()),
+	}
+	if host := os.Getenv("CACHE_TEST_REDIS"); host != "" {
+		rc := NewRedisCache("test-"+t.Name(), host, 1, 10, 15)
+		if _, err := rc.(*redisCache).client().Do("PING"); err != nil {
+			t.Fatalf("redis not available: %v", err)
+		}
+		caches["redis"] = rc
+	}
+	return caches
+}
+
+func TestPutTTL(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			if !cache.Put("sub", 1, 50*time.Millisecond) {
+				t.Fatal("sub-second ttl rejected")
+			}
+			if ttl := cache.TTL("sub"); ttl <= 0 || ttl > 50*time.Millisecond {
+				t.Fatalf("sub-second ttl %s", ttl)
+			}
+			time.Sleep(70 * time.Millisecond)
+			if cache.Exists("sub") {
+				t.Fatal("sub-second ttl item not expired")
+			}
+
+			if cache.Put("zero", 1, 0) || cache.Exists("zero") {
+				t.Fatal("zero ttl accepted")
+			}
+			if cache.Put("negative", 1, -time.Second) || cache.Exists("negative") {
+				t.Fatal("negative ttl accepted")
+			}
+
+			cache.Put("kept", 1, time.Minute)
+			if cache.Put("kept", 2, 0) || cache.Int("kept", 0) != 1 {
+				t.Fatal("rejected put changed existing item")
+			}
+			cache.Forget("kept")
+		})
+	}
+}
+
+func TestTTLInfo(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			if info := cache.TTLInfo("missing"); info != (TTLInfo{}) {
+				t.Fatalf("missing item %+v", info)
+			}
+
+			cache.PutForever("forever", 1)
+			if info := cache.TTLInfo("forever"); info != (TTLInfo{Exists: true}) {
+				t.Fatalf("item without expiration %+v", info)
+			}
+
+			cache.Put("expiring", 1, time.Minute)
+			info := cache.TTLInfo("expiring")
+			if !info.Exists || !info.HasExpiry || info.Remaining <= 59*time.Second || info.Remaining > time.Minute {
+				t.Fatalf("expiring item %+v", info)
+			}
+
+			limiter := NewRateLimiter("limiter", 1, time.Minute, cache)
+			if limiter.AvailableIn() != 0 {
+				t.Fatal("available in before first hit")
+			}
+			limiter.Hit()
+			limiter.Hit()
+			if in := limiter.AvailableIn(); in <= 59*time.Second || in > time.Minute {
+				t.Fatalf("available in %s", in)
+			}
+
+			cache.Forget("forever")
+			cache.Forget("expiring")
+			limiter.Reset()
+		})
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			defer cache.Forget("item")
+			if cache.Touch("item", time.Minute) || cache.ExpireAt("item", time.Now().Add(time.Minute)) || cache.Persist("item") {
+				t.Fatal("missing item expiration changed")
+			}
+
+			cache.Put("item", 1, time.Second)
+			if !cache.Touch("item", time.Minute) {
+				t.Fatal("touch failed")
+			}
+			if ttl := cache.TTL("item"); ttl <= 59*time.Second || cache.Int("item", 0) != 1 {
+				t.Fatalf("touched item ttl %s", ttl)
+			}
+			if cache.Touch("item", 0) || cache.Touch("item", -time.Second) {
+				t.Fatal("non-positive touch accepted")
+			}
+
+			if !cache.ExpireAt("item", time.Now().Add(2*time.Minute)) {
+				t.Fatal("expire at failed")
+			}
+			if ttl := cache.TTL("item"); ttl <= 119*time.Second || ttl > 2*time.Minute {
+				t.Fatalf("expire at ttl %s", ttl)
+			}
+			if cache.ExpireAt("item", time.Now().Add(-time.Second)) {
+				t.Fatal("past expire at accepted")
+			}
+
+			if !cache.Persist("item") {
+				t.Fatal("persist failed")
+			}
+			if info := cache.TTLInfo("item"); info != (TTLInfo{Exists: true}) || cache.Int("item", 0) != 1 {
+				t.Fatalf("persisted item %+v", info)
+			}
+
+			cache.Put("item", 1, 50*time.Millisecond)
+			time.Sleep(70 * time.Millisecond)
+			if cache.Touch("item", time.Minute) || cache.Persist("item") || cache.Exists("item") {
+				t.Fatal("expired item expiration changed")
+			}
+		})
+	}
+}
+
+func TestFileConcurrentUpdate(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	cache.Put("counter", 0, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cache.Increment("counter")
+		}()
+		go func() {
+			defer wg.Done()
+			cache.Touch("counter", time.Hour)
+		}()
+	}
+	wg.Wait()
+	if count := cache.Int("counter", 0); count != 20 {
+		t.Fatalf("concurrent increments lost, counter %d", count)
+	}
+	if ttl := cache.TTL("counter"); ttl <= 59*time.Minute {
+		t.Fatalf("concurrent touch lost, ttl %s", ttl)
+	}
+
+	cache.Put("forgotten", 1, time.Minute)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cache.Forget("forgotten")
+	}()
+	go func() {
+		defer wg.Done()
+		cache.Persist("forgotten")
+	}()
+	wg.Wait()
+	if cache.Exists("forgotten") {
+		t.Fatal("persist undid forget")
+	}
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLockTimeout error returned when lock not acquired in time
+var ErrLockTimeout = errors.New("cache: lock timeout")
+
+// ErrLockNotSupported error returned when atomic operation used with cache driver that not support locking
+var ErrLockNotSupported = errors.New("cache: cache driver not support locking")
+
+// Lock interface for distributed lock
+type Lock interface {
+	// Acquire try to acquire lock until timeout passed
+	Acquire(timeout time.Duration) bool
+	// TryAcquire try to acquire lock once
+	TryAcquire() bool
+	// Release release lock if owned by this lock instance
+	Release() bool
+	// Refresh reset lock ttl if owned by this lock instance
+	Refresh() bool
+	// Owner get lock owner token
+	Owner() string
+}
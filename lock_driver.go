@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/gobardofw/utils"
+)
+
+// lockRetryDelay delay between acquire attempts
+const lockRetryDelay = 50 * time.Millisecond
+
+// syncLockTTL ttl and acquire timeout of synchronize lock
+const syncLockTTL = 5 * time.Second
+
+// locker interface for cache drivers that support locking
+type locker interface {
+	acquireLock(key string, token string, ttl time.Duration) bool
+	releaseLock(key string, token string) bool
+	refreshLock(key string, token string, ttl time.Duration) bool
+	lockHeld(key string) bool
+}
+
+// randomToken generate a random owner token
+func randomToken() (string, error) {
+	return utils.RandomStringFromCharset(32, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+}
+
+// synchronize run action while holding lock on key
+// action not run if cache driver not support locking or lock not acquired
+func synchronize(cache Cache, key string, action func()) error {
+	lock := NewLock(key, syncLockTTL, cache)
+	if lock == nil {
+		return ErrLockNotSupported
+	}
+	if !lock.Acquire(syncLockTTL) {
+		return ErrLockTimeout
+	}
+	defer lock.Release()
+	action()
+	return nil
+}
+
+// compareAndSwap atomically replace int64 item if current value equals old, zero old means item not exists
+// use lua script on redis and synchronize on other drivers
+func compareAndSwap(cache Cache, key string, old int64, new int64, ttl time.Duration) bool {
+	if rc, ok := cache.(*redisCache); ok {
+		return rc.compareAndSwap(key, old, new, ttl)
+	}
+	swapped := false
+	synchronize(cache, key, func() {
+		if cache.Int64(key, 0) == old {
+			swapped = cache.Put(key, new, ttl)
+		}
+	})
+	return swapped
+}
+
+// incrementWithin atomically increment counter and return new value
+// counter created with window expiration if not exists
+// use lua script on redis and synchronize on other drivers
+func incrementWithin(cache Cache, key string, window time.Duration) (int64, bool) {
+	if rc, ok := cache.(*redisCache); ok {
+		return rc.incrementWithin(key, window)
+	}
+	var count int64
+	stored := false
+	synchronize(cache, key, func() {
+		count = cache.Int64(key, 0) + 1
+		if count > 1 && cache.Set(key, count) {
+			stored = true
+			return
+		}
+		count = 1
+		stored = cache.Put(key, count, window)
+	})
+	return count, stored
+}
+
+// decrementExisting atomically decrement counter if exists and positive, ttl not changed
+// use lua script on redis and synchronize on other drivers
+func decrementExisting(cache Cache, key string) bool {
+	if rc, ok := cache.(*redisCache); ok {
+		return rc.decrementExisting(key)
+	}
+	decremented := false
+	synchronize(cache, key, func() {
+		if cache.Int64(key, 0) > 0 {
+			decremented = cache.Decrement(key)
+		}
+	})
+	return decremented
+}
+
+// lockDriver distributed lock driver
+type lockDriver struct {
+	Key   string
+	TTL   time.Duration
+	Token string
+	store locker
+}
+
+func (lock *lockDriver) init(name string, ttl time.Duration, store locker) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	lock.Key = name + "-lock"
+	lock.TTL = ttl
+	lock.Token = token
+	lock.store = store
+	return nil
+}
+
+// Acquire try to acquire lock until timeout passed
+func (lock *lockDriver) Acquire(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if lock.TryAcquire() {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// TryAcquire try to acquire lock once
+func (lock *lockDriver) TryAcquire() bool {
+	return lock.store.acquireLock(lock.Key, lock.Token, lock.TTL)
+}
+
+// Release release lock if owned by this lock instance
+func (lock *lockDriver) Release() bool {
+	return lock.store.releaseLock(lock.Key, lock.Token)
+}
+
+// Refresh reset lock ttl if owned by this lock instance
+func (lock *lockDriver) Refresh() bool {
+	return lock.store.refreshLock(lock.Key, lock.Token, lock.TTL)
+}
+
+// Owner get lock owner token
+func (lock *lockDriver) Owner() string {
+	return lock.Token
+}
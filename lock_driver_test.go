@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// plainCache cache driver without locking support
+type plainCache struct {
+	Cache
+}
+
+func TestSynchronizeNotSupported(t *testing.T) {
+	cache := plainCache{NewFileCache("test", t.TempDir())}
+	run := false
+	if err := synchronize(cache, "key", func() { run = true }); err != ErrLockNotSupported || run {
+		t.Fatal("action run without lock")
+	}
+
+	vc, err := NewVerificationCode("code", time.Minute, []byte("secret"), cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vc.Verify("12345"); err != ErrLockNotSupported {
+		t.Fatalf("verify error %v", err)
+	}
+	if res := NewRateLimiter("limiter", 5, time.Minute, cache).Hit(); res.Allowed {
+		t.Fatal("hit allowed without lock")
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/gobardofw/utils"
+)
+
+// staleLockAge age after which unreadable lock file treated as abandoned
+// lock file is unreadable only while created or if holder crashed while creating it
+const staleLockAge = time.Second
+
+// lockAbandoned check if lock file expired or unreadable for longer than staleLockAge
+// removed lock file counts as abandoned
+func lockAbandoned(path string) bool {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	rec := cacheRecord{}
+	if rec.Deserialize(string(bytes)) {
+		return rec.IsExpired()
+	}
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+// claimLock run action while holding claim file of lock path
+// release, refresh and take over run under claim so lock file not changed between check and update
+// claim held by other waited, claim file left by crashed process removed after staleLockAge
+func claimLock(path string, action func()) bool {
+	claim := path + ".claim"
+	deadline := time.Now().Add(2 * staleLockAge)
+	for time.Now().Before(deadline) {
+		f, err := os.OpenFile(claim, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			defer os.Remove(claim)
+			action()
+			return true
+		}
+		if !os.IsExist(err) {
+			return false
+		}
+		if info, err := os.Stat(claim); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(claim)
+			continue
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+// takeOver remove abandoned lock file
+func takeOver(path string) bool {
+	removed := false
+	claimLock(path, func() {
+		if lockAbandoned(path) {
+			err := os.Remove(path)
+			removed = err == nil || os.IsNotExist(err)
+		}
+	})
+	return removed
+}
+
+func (c *fileCache) acquireLock(key string, token string, ttl time.Duration) bool {
+	utils.CreateDirectory(c.dir)
+	record := cacheRecord{
+		TTL:  time.Now().UTC().Add(ttl),
+		Data: token,
+	}
+	encoded, ok := record.Serialize()
+	if !ok {
+		return false
+	}
+
+	// second try run only if abandoned lock file removed
+	path := c.pathResolver(key)
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(encoded)
+			if cErr := f.Close(); err == nil {
+				err = cErr
+			}
+			if err != nil {
+				c.delete(key)
+				return false
+			}
+			return true
+		}
+		if !os.IsExist(err) {
+			return false
+		}
+
+		// lock file being written counts as locked
+		if !lockAbandoned(path) || !takeOver(path) {
+			return false
+		}
+	}
+	return false
+}
+
+// owned check if lock not expired and owned by token
+func (c *fileCache) owned(key string, token string) (*cacheRecord, bool) {
+	rec, exists := c.readRecord(key)
+	if !exists || rec.IsExpired() || rec.Data != token {
+		return nil, false
+	}
+	return rec, true
+}
+
+func (c *fileCache) releaseLock(key string, token string) bool {
+	released := false
+	claimLock(c.pathResolver(key), func() {
+		if _, ok := c.owned(key, token); ok {
+			released = c.delete(key)
+		}
+	})
+	return released
+}
+
+func (c *fileCache) refreshLock(key string, token string, ttl time.Duration) bool {
+	refreshed := false
+	claimLock(c.pathResolver(key), func() {
+		rec, ok := c.owned(key, token)
+		if !ok {
+			return
+		}
+		rec.TTL = time.Now().UTC().Add(ttl)
+		encoded, ok := rec.Serialize()
+		if !ok {
+			return
+		}
+
+		// write to temp file and rename so lock file never partially written
+		path := c.pathResolver(key)
+		temp := path + "." + token
+		if ioutil.WriteFile(temp, []byte(encoded), 0644) != nil || os.Rename(temp, path) != nil {
+			os.Remove(temp)
+			return
+		}
+		refreshed = true
+	})
+	return refreshed
+}
+
+func (c *fileCache) lockHeld(key string) bool {
+	return !lockAbandoned(c.pathResolver(key))
+}
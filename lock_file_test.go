@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLockUnreadable(t *testing.T) {
+	fc := NewFileCache("test", t.TempDir()).(*fileCache)
+	path := fc.pathResolver("job-lock")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock := NewLock("job", time.Minute, fc)
+	if lock.TryAcquire() {
+		t.Fatal("lock file being written must count as locked")
+	}
+
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !lock.TryAcquire() {
+		t.Fatal("abandoned unreadable lock file must be taken over")
+	}
+}
+
+func TestFileLockExpiredTakeOver(t *testing.T) {
+	fc := NewFileCache("test", t.TempDir())
+	if !NewLock("job", 10*time.Millisecond, fc).TryAcquire() {
+		t.Fatal("first acquire failed")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var acquired int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if NewLock("job", time.Minute, fc).TryAcquire() {
+				atomic.AddInt32(&acquired, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if acquired != 1 {
+		t.Fatalf("expired lock taken over %d times", acquired)
+	}
+}
+
+func TestFileLockRefresh(t *testing.T) {
+	fc := NewFileCache("test", t.TempDir())
+	lock := NewLock("job", 50*time.Millisecond, fc)
+	if !lock.TryAcquire() {
+		t.Fatal("acquire failed")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !lock.Refresh() {
+		t.Fatal("refresh failed")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if NewLock("job", time.Minute, fc).TryAcquire() {
+		t.Fatal("refreshed lock acquired by other")
+	}
+	if !lock.Release() {
+		t.Fatal("release failed")
+	}
+}
+
+func TestFileLockReleaseAfterTakeOver(t *testing.T) {
+	fc := NewFileCache("test", t.TempDir())
+	expired := NewLock("job", 10*time.Millisecond, fc)
+	if !expired.TryAcquire() {
+		t.Fatal("first acquire failed")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	holder := NewLock("job", time.Minute, fc)
+	if !holder.TryAcquire() {
+		t.Fatal("expired lock not taken over")
+	}
+	if expired.Release() || expired.Refresh() {
+		t.Fatal("expired owner changed lock of new holder")
+	}
+	if NewLock("job", time.Minute, fc).TryAcquire() {
+		t.Fatal("lock of new holder removed")
+	}
+	if !holder.Release() {
+		t.Fatal("release failed")
+	}
+}
+
+func TestFileLockStaleClaim(t *testing.T) {
+	fc := NewFileCache("test", t.TempDir()).(*fileCache)
+	lock := NewLock("job", time.Minute, fc)
+	if !lock.TryAcquire() {
+		t.Fatal("acquire failed")
+	}
+
+	claim := fc.pathResolver("job-lock") + ".claim"
+	if err := ioutil.WriteFile(claim, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(claim, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !lock.Release() {
+		t.Fatal("claim left by crashed process blocked release")
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var redisReleaseLockScript = redis.NewScript(-1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var redisRefreshLockScript = redis.NewScript(-1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+func (c *redisCache) acquireLock(key string, token string, ttl time.Duration) bool {
+	conn := c.client()
+	defer conn.Close()
+	_, err := redis.String(conn.Do("SET", c.prefixer(key), token, "NX", "PX", milliseconds(ttl)))
+	return err == nil
+}
+
+func (c *redisCache) releaseLock(key string, token string) bool {
+	reply, err := redis.Int(c.eval(redisReleaseLockScript, []string{key}, token))
+	return err == nil && reply == 1
+}
+
+func (c *redisCache) refreshLock(key string, token string, ttl time.Duration) bool {
+	reply, err := redis.Int(c.eval(redisRefreshLockScript, []string{key}, token, milliseconds(ttl)))
+	return err == nil && reply == 1
+}
+
+func (c *redisCache) lockHeld(key string) bool {
+	conn := c.client()
+	defer conn.Close()
+	count, err := redis.Int(conn.Do("EXISTS", c.prefixer(key)))
+	return err == nil && count == 1
+}
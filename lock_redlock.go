@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// redlockDriftFactor clock drift factor of redlock algorithm
+const redlockDriftFactor = 0.01
+
+// redlockDriver quorum lock across multiple independent cache instances
+type redlockDriver struct {
+	Key    string
+	TTL    time.Duration
+	Token  string
+	stores []locker
+}
+
+func (lock *redlockDriver) init(name string, ttl time.Duration, stores []locker) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	lock.Key = name + "-lock"
+	lock.TTL = ttl
+	lock.Token = token
+	lock.stores = stores
+	return nil
+}
+
+// quorum get minimum number of instances must agree
+func (lock *redlockDriver) quorum() int {
+	return len(lock.stores)/2 + 1
+}
+
+// drift get clock drift for lock ttl
+func (lock *redlockDriver) drift() time.Duration {
+	return time.Duration(float64(lock.TTL)*redlockDriftFactor) + 2*time.Millisecond
+}
+
+// each run action on all instances in parallel and return number of succeeded instances
+func (lock *redlockDriver) each(action func(store locker) bool) int {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	count := 0
+	for _, store := range lock.stores {
+		wg.Add(1)
+		go func(store locker) {
+			defer wg.Done()
+			if action(store) {
+				mutex.Lock()
+				count++
+				mutex.Unlock()
+			}
+		}(store)
+	}
+	wg.Wait()
+	return count
+}
+
+// hold run action and check if lock is valid on quorum of instances
+// release lock on all instances on failure
+func (lock *redlockDriver) hold(action func(store locker) bool) bool {
+	start := time.Now()
+	count := lock.each(action)
+	validity := lock.TTL - time.Since(start) - lock.drift()
+	if count >= lock.quorum() && validity > 0 {
+		return true
+	}
+	lock.Release()
+	return false
+}
+
+// Acquire try to acquire lock until timeout passed
+func (lock *redlockDriver) Acquire(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if lock.TryAcquire() {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// TryAcquire try to acquire lock once
+func (lock *redlockDriver) TryAcquire() bool {
+	return lock.hold(func(store locker) bool {
+		return store.acquireLock(lock.Key, lock.Token, lock.TTL)
+	})
+}
+
+// Release release lock if owned by this lock instance
+func (lock *redlockDriver) Release() bool {
+	return lock.each(func(store locker) bool {
+		return store.releaseLock(lock.Key, lock.Token)
+	}) >= lock.quorum()
+}
+
+// Refresh reset lock ttl if owned by this lock instance
+func (lock *redlockDriver) Refresh() bool {
+	return lock.hold(func(store locker) bool {
+		return store.refreshLock(lock.Key, lock.Token, lock.TTL)
+	})
+}
+
+// Owner get lock owner token
+func (lock *redlockDriver) Owner() string {
+	return lock.Token
+}
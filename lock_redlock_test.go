@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLocker in-process locker for quorum tests
+type fakeLocker struct {
+	mutex    sync.Mutex
+	down     bool
+	delay    time.Duration
+	owners   map[string]string
+	releases int
+}
+
+func newFakeLocker() *fakeLocker {
+	return &fakeLocker{owners: make(map[string]string)}
+}
+
+func (f *fakeLocker) acquireLock(key string, token string, ttl time.Duration) bool {
+	time.Sleep(f.delay)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.down {
+		return false
+	}
+	if _, ok := f.owners[key]; ok {
+		return false
+	}
+	f.owners[key] = token
+	return true
+}
+
+func (f *fakeLocker) releaseLock(key string, token string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.releases++
+	if f.down || f.owners[key] != token {
+		return false
+	}
+	delete(f.owners, key)
+	return true
+}
+
+func (f *fakeLocker) refreshLock(key string, token string, ttl time.Duration) bool {
+	time.Sleep(f.delay)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return !f.down && f.owners[key] == token
+}
+
+func (f *fakeLocker) lockHeld(key string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	_, ok := f.owners[key]
+	return ok
+}
+
+func newTestRedlock(t *testing.T, ttl time.Duration, stores ...*fakeLocker) *redlockDriver {
+	lockers := make([]locker, 0, len(stores))
+	for _, store := range stores {
+		lockers = append(lockers, store)
+	}
+	lock := new(redlockDriver)
+	if err := lock.init("job", ttl, lockers); err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
+
+func TestRedlockQuorumReached(t *testing.T) {
+	a, b, c := newFakeLocker(), newFakeLocker(), newFakeLocker()
+	c.down = true
+	lock := newTestRedlock(t, time.Second, a, b, c)
+	if !lock.TryAcquire() {
+		t.Fatal("lock not acquired on 2 of 3 instances")
+	}
+	if !a.lockHeld(lock.Key) || !b.lockHeld(lock.Key) {
+		t.Fatal("lock not held on available instances")
+	}
+	if newTestRedlock(t, time.Second, a, b, c).TryAcquire() {
+		t.Fatal("held lock acquired by other")
+	}
+	if !lock.Release() || a.lockHeld(lock.Key) || b.lockHeld(lock.Key) {
+		t.Fatal("lock not released")
+	}
+}
+
+func TestRedlockQuorumNotReached(t *testing.T) {
+	a, b, c := newFakeLocker(), newFakeLocker(), newFakeLocker()
+	b.down = true
+	c.down = true
+	lock := newTestRedlock(t, time.Second, a, b, c)
+	if lock.TryAcquire() {
+		t.Fatal("lock acquired on 1 of 3 instances")
+	}
+	if a.lockHeld(lock.Key) {
+		t.Fatal("lock not released after failed acquire")
+	}
+	for _, store := range []*fakeLocker{a, b, c} {
+		if store.releases != 1 {
+			t.Fatal("release not sent to all instances")
+		}
+	}
+}
+
+func TestRedlockValidity(t *testing.T) {
+	lock := newTestRedlock(t, time.Second, newFakeLocker())
+	if drift := lock.drift(); drift != 12*time.Millisecond {
+		t.Fatalf("drift %s, expected 12ms", drift)
+	}
+
+	// acquire within ttl but not within ttl minus drift
+	a, b, c := newFakeLocker(), newFakeLocker(), newFakeLocker()
+	for _, store := range []*fakeLocker{a, b, c} {
+		store.delay = 98 * time.Millisecond
+	}
+	lock = newTestRedlock(t, 100*time.Millisecond, a, b, c)
+	if lock.TryAcquire() {
+		t.Fatal("lock acquired after validity passed")
+	}
+	if a.lockHeld(lock.Key) || b.lockHeld(lock.Key) || c.lockHeld(lock.Key) {
+		t.Fatal("lock not released after validity passed")
+	}
+}
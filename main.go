@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"io"
 	"time"
 )
 
@@ -18,18 +19,233 @@ func NewFileCache(prefix string, dir string) Cache {
 	return fc
 }
 
-// NewRateLimiter create a new rate limiter
-func NewRateLimiter(key string, maxAttempts uint32, ttl time.Duration, cache Cache) RateLimiter {
+// NewLock create a new distributed lock
+// return nil if cache driver not support locking
+func NewLock(name string, ttl time.Duration, cache Cache) Lock {
+	store, ok := cache.(locker)
+	if !ok {
+		return nil
+	}
+	lock := new(lockDriver)
+	if err := lock.init(name, ttl, store); err != nil {
+		return nil
+	}
+	return lock
+}
+
+// NewRedLock create a new quorum lock across multiple independent cache instances (redlock algorithm)
+// return nil if no cache passed or any cache driver not support locking
+func NewRedLock(name string, ttl time.Duration, caches ...Cache) Lock {
+	if len(caches) == 0 {
+		return nil
+	}
+	stores := make([]locker, 0, len(caches))
+	for _, cache := range caches {
+		store, ok := cache.(locker)
+		if !ok {
+			return nil
+		}
+		stores = append(stores, store)
+	}
+	lock := new(redlockDriver)
+	if err := lock.init(name, ttl, stores); err != nil {
+		return nil
+	}
+	return lock
+}
+
+// NewSemaphore create a new distributed semaphore allowing limit concurrent leases
+// leases expire after leaseTTL if not renewed
+// return nil if cache driver not support locking
+func NewSemaphore(key string, limit uint32, leaseTTL time.Duration, cache Cache) Semaphore {
+	store, ok := cache.(locker)
+	if !ok {
+		return nil
+	}
+	sem := new(semaphoreDriver)
+	if err := sem.init(key, limit, leaseTTL, cache, store); err != nil {
+		return nil
+	}
+	return sem
+}
+
+// NewRateLimiter create a new fixed window rate limiter
+// window started on first hit and restarted on first hit after expiration
+func NewRateLimiter(key string, maxAttempts uint32, ttl time.Duration, cache Cache) WindowRateLimiter {
+	return NewRateLimiterWithOptions(key, maxAttempts, ttl, cache, RateLimiterOptions{})
+}
+
+// NewRateLimiterWithOptions create a new fixed window rate limiter with options
+// window expiration decided by options clock, window keys still expire after ttl in cache
+func NewRateLimiterWithOptions(key string, maxAttempts uint32, ttl time.Duration, cache Cache, options RateLimiterOptions) WindowRateLimiter {
 	limiter := new(rateLimiterDriver)
-	limiter.init(key, maxAttempts, ttl, cache)
+	limiter.init(key, maxAttempts, ttl, cache, options)
+	return limiter
+}
+
+// NewSlidingWindowLimiter create a new sliding window log rate limiter
+func NewSlidingWindowLimiter(key string, maxAttempts uint32, window time.Duration, cache Cache) RateLimiter {
+	limiter := new(slidingLogLimiter)
+	limiter.init(key, maxAttempts, window, cache)
+	return limiter
+}
+
+// NewSlidingWindowCounterLimiter create a new sliding window counter rate limiter
+func NewSlidingWindowCounterLimiter(key string, maxAttempts uint32, window time.Duration, cache Cache) RateLimiter {
+	limiter := new(slidingCounterLimiter)
+	limiter.init(key, maxAttempts, window, cache)
+	return limiter
+}
+
+// NewGCRALimiter create a new generic cell rate algorithm rate limiter
+// rate is number of allowed hits per second and burst is maximum number of hits at once
+// return nil if rate is not positive or out of nanosecond precision range
+func NewGCRALimiter(key string, rate float64, burst uint32, cache Cache) WeightedRateLimiter {
+	limiter := new(gcraLimiter)
+	if err := limiter.init(key, rate, burst, cache); err != nil {
+		return nil
+	}
 	return limiter
 }
 
+// NewTokenBucketLimiter create a new token bucket rate limiter
+// rate is number of tokens refilled per second and burst is bucket capacity
+// token bucket is equivalent to gcra and store bucket state as single timestamp
+// return nil if rate is not positive or out of nanosecond precision range
+func NewTokenBucketLimiter(key string, rate float64, burst uint32, cache Cache) WeightedRateLimiter {
+	return NewGCRALimiter(key, rate, burst, cache)
+}
+
+// NewCompositeLimiter create a new rate limiter enforcing multiple fixed window limits at once
+// hit denied if any limit exhausted, limits with empty name named by index
+// return nil if limit names not unique
+func NewCompositeLimiter(key string, cache Cache, limits ...Limit) CompositeLimiter {
+	limiter := new(compositeLimiter)
+	if err := limiter.init(key, cache, limits); err != nil {
+		return nil
+	}
+	return limiter
+}
+
+// NewBackoffLimiter create a new rate limiter with progressive lockout
+// each lockout use next schedule duration (last one repeated) and strike level decay after quiet period
+// DefaultBackoffSchedule used if schedule is empty
+func NewBackoffLimiter(key string, maxAttempts uint32, window time.Duration, schedule []time.Duration, decay time.Duration, cache Cache) BackoffLimiter {
+	return NewBackoffLimiterWithOptions(key, maxAttempts, window, schedule, decay, cache, RateLimiterOptions{})
+}
+
+// NewBackoffLimiterWithOptions create a new rate limiter with progressive lockout and options
+// options clock decide attempts window expiration only, lockout and strikes expire by cache ttl
+func NewBackoffLimiterWithOptions(key string, maxAttempts uint32, window time.Duration, schedule []time.Duration, decay time.Duration, cache Cache, options RateLimiterOptions) BackoffLimiter {
+	limiter := new(backoffLimiter)
+	limiter.init(key, maxAttempts, window, schedule, decay, cache, options)
+	return limiter
+}
+
+// NewRegistry create a new rate limiter registry
+// registry limiters use fixed window rate limiter
+func NewRegistry(prefix string, cache Cache) Registry {
+	r := new(registryDriver)
+	r.init(prefix, cache)
+	return r
+}
+
+// NewQuota create a new quota counter reset at calendar period boundaries of location
+// UTC used if location is nil
+func NewQuota(key string, limit uint32, period Period, location *time.Location, cache Cache) Quota {
+	return NewQuotaWithOptions(key, limit, period, location, cache, QuotaOptions{})
+}
+
+// NewQuotaWithOptions create a new quota counter with options
+// options clock decide current period, period counter expire after time left in period
+func NewQuotaWithOptions(key string, limit uint32, period Period, location *time.Location, cache Cache, options QuotaOptions) Quota {
+	quota := new(quotaDriver)
+	quota.init(key, limit, period, location, cache, options)
+	return quota
+}
+
 // NewVerificationCode create a new verification code manager instance
-func NewVerificationCode(key string, ttl time.Duration, cache Cache) VerificationCode {
+// codes stored as hash keyed with secret, return ErrMissingSecret if secret is empty
+func NewVerificationCode(key string, ttl time.Duration, secret []byte, cache Cache) (VerificationCode, error) {
+	return NewVerificationCodeWithOptions(key, ttl, cache, VerificationOptions{Secret: secret})
+}
+
+// NewVerificationCodeWithOptions create a new verification code manager instance with options
+// return ErrMissingSecret if options secret is empty
+func NewVerificationCodeWithOptions(key string, ttl time.Duration, cache Cache, options VerificationOptions) (VerificationCode, error) {
 	vc := new(vcDriver)
-	vc.Key = key
-	vc.TTL = ttl
-	vc.Cache = cache
-	return vc
+	if err := vc.init(key, ttl, cache, options); err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
+
+// NewVerificationToken create a new single use url safe token manager
+// tokens stored as hash keyed with secret and expire after ttl, return ErrMissingSecret if secret is empty
+func NewVerificationToken(key string, ttl time.Duration, secret []byte, cache Cache) (VerificationToken, error) {
+	token := new(tokenDriver)
+	if err := token.init(key, ttl, secret, cache); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// NewDispatcher create a new verification code dispatcher
+// tpl is text/template with Code, To, Purpose and Payload fields
+// return error if template is invalid
+func NewDispatcher(code VerificationCode, sender Sender, tpl string) (Dispatcher, error) {
+	dispatcher := new(dispatcherDriver)
+	if err := dispatcher.init(code, sender, tpl); err != nil {
+		return nil, err
+	}
+	return dispatcher, nil
+}
+
+// NewMemorySender create a new sender that keep messages in memory
+func NewMemorySender() *MemorySender {
+	return new(MemorySender)
+}
+
+// NewWriterSender create a new sender that write messages to writer (e.g. os.Stdout)
+func NewWriterSender(writer io.Writer) Sender {
+	sender := new(writerSender)
+	sender.Writer = writer
+	return sender
+}
+
+// NewFileSender create a new sender that append messages to file
+func NewFileSender(path string) Sender {
+	sender := new(fileSender)
+	sender.Path = path
+	return sender
+}
+
+// NewRecoveryCodes create a new account recovery codes manager
+// codes stored as hash keyed with secret and never expire, return ErrMissingSecret if secret is empty
+func NewRecoveryCodes(key string, secret []byte, cache Cache) (RecoveryCodes, error) {
+	recovery := new(recoveryDriver)
+	if err := recovery.init(key, secret, cache); err != nil {
+		return nil, err
+	}
+	return recovery, nil
+}
+
+// NewTOTP create a new rfc 6238 time based one-time password manager
+// return nil if digits out of 6 to 10 range
+func NewTOTP(key string, secret []byte, options OTPOptions, cache Cache) OTP {
+	otp := new(otpDriver)
+	if err := otp.init(key, secret, options, cache, false); err != nil {
+		return nil
+	}
+	return otp
+}
+
+// NewHOTP create a new rfc 4226 counter based one-time password manager
+// return nil if digits out of 6 to 10 range
+func NewHOTP(key string, secret []byte, options OTPOptions, cache Cache) OTP {
+	otp := new(otpDriver)
+	if err := otp.init(key, secret, options, cache, true); err != nil {
+		return nil
+	}
+	return otp
 }
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyResolver resolve rate limiter key from request
+// empty key skip rate limiting for request
+type KeyResolver func(r *http.Request) string
+
+// LimiterResolver create rate limiter for key
+type LimiterResolver func(key string) RateLimiter
+
+// KeyByIP resolve key from request remote ip
+func KeyByIP(prefix string) KeyResolver {
+	return func(r *http.Request) string {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		if ip == "" {
+			return ""
+		}
+		return prefix + "-" + ip
+	}
+}
+
+// KeyByHeader resolve key from request header (e.g. user id or api key)
+func KeyByHeader(prefix string, header string) KeyResolver {
+	return func(r *http.Request) string {
+		value := r.Header.Get(header)
+		if value == "" {
+			return ""
+		}
+		return prefix + "-" + value
+	}
+}
+
+// KeyByRoute resolve key from request method, path and resolver key
+func KeyByRoute(resolver KeyResolver) KeyResolver {
+	return func(r *http.Request) string {
+		key := resolver(r)
+		if key == "" {
+			return ""
+		}
+		return key + "-" + r.Method + "-" + r.URL.Path
+	}
+}
+
+// seconds convert duration to seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// MiddlewareOptions rate limiter middleware options
+type MiddlewareOptions struct {
+	// FailOpen pass request to next handler if rate limiter state not available
+	// request rejected with 503 status if false
+	FailOpen bool
+}
+
+// NewRateLimiterMiddleware create a new net/http rate limiter middleware
+// set RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers on response
+// and reject request with 429 status and Retry-After header if rate limiter locked
+// request rejected with 503 status if rate limiter state not available
+func NewRateLimiterMiddleware(key KeyResolver, limiter LimiterResolver) func(http.Handler) http.Handler {
+	return NewRateLimiterMiddlewareWithOptions(key, limiter, MiddlewareOptions{})
+}
+
+// NewRateLimiterMiddlewareWithOptions create a new net/http rate limiter middleware with options
+func NewRateLimiterMiddlewareWithOptions(key KeyResolver, limiter LimiterResolver, options MiddlewareOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res := limiter(k).Hit()
+			if res.Err != nil {
+				if options.FailOpen {
+					next.ServeHTTP(w, r)
+				} else {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				}
+				return
+			}
+			w.Header().Set("RateLimit-Limit", strconv.FormatUint(uint64(res.Limit), 10))
+			w.Header().Set("RateLimit-Remaining", strconv.FormatUint(uint64(res.Remaining), 10))
+			w.Header().Set("RateLimit-Reset", seconds(res.ResetIn))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyResolvers(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-User", "42")
+
+	tests := []struct {
+		name     string
+		resolver KeyResolver
+		expected string
+	}{
+		{"ip", KeyByIP("ip"), "ip-10.0.0.1"},
+		{"header", KeyByHeader("user", "X-User"), "user-42"},
+		{"missing header", KeyByHeader("key", "X-Api-Key"), ""},
+		{"route", KeyByRoute(KeyByIP("ip")), "ip-10.0.0.1-POST-/login"},
+		{"route without key", KeyByRoute(KeyByHeader("key", "X-Api-Key")), ""},
+	}
+	for _, test := range tests {
+		if key := test.resolver(r); key != test.expected {
+			t.Errorf("%s: got %q, expected %q", test.name, key, test.expected)
+		}
+	}
+
+	r.RemoteAddr = "10.0.0.2"
+	if key := KeyByIP("ip")(r); key != "ip-10.0.0.2" {
+		t.Errorf("ip without port: got %q", key)
+	}
+	r.RemoteAddr = ""
+	if key := KeyByIP("ip")(r); key != "" {
+		t.Errorf("empty remote address: got %q", key)
+	}
+}
+
+// serve run request through handler and return response
+func serve(handler http.Handler, header string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		r.Header.Set("X-User", header)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	handler := NewRateLimiterMiddleware(KeyByHeader("user", "X-User"), func(key string) RateLimiter {
+		return NewRateLimiter(key, 2, time.Minute, cache)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, remaining := range []string{"1", "0"} {
+		w := serve(handler, "1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d rejected with %d", i+1, w.Code)
+		}
+		if h := w.Header(); h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != remaining || h.Get("RateLimit-Reset") != "60" || h.Get("Retry-After") != "" {
+			t.Fatalf("request %d headers %v", i+1, h)
+		}
+	}
+
+	w := serve(handler, "1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("exceeded request %d %v", w.Code, w.Header())
+	}
+	if w := serve(handler, "2"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatal("other key limited")
+	}
+	if w := serve(handler, ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatal("request without key limited")
+	}
+}
+
+func TestRateLimiterMiddlewareUnavailable(t *testing.T) {
+	cache := plainCache{NewFileCache("test", t.TempDir())}
+	limiter := func(key string) RateLimiter {
+		return NewRateLimiter(key, 2, time.Minute, cache)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	closed := NewRateLimiterMiddleware(KeyByHeader("user", "X-User"), limiter)(next)
+	if w := serve(closed, "1"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "" {
+		t.Fatalf("fail closed responded %d", w.Code)
+	}
+
+	open := NewRateLimiterMiddlewareWithOptions(KeyByHeader("user", "X-User"), limiter, MiddlewareOptions{FailOpen: true})(next)
+	if w := serve(open, "1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("fail open responded %d", w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import "time"
+
+// EventType security event type
+type EventType string
+
+const (
+	// EventCodeGenerated verification code generated
+	EventCodeGenerated EventType = "code.generated"
+	// EventCodeVerified verification code matched and consumed
+	EventCodeVerified EventType = "code.verified"
+	// EventCodeFailed verification code not matched
+	EventCodeFailed EventType = "code.failed"
+	// EventCodeLocked verification code invalidated after maximum failed attempts
+	EventCodeLocked EventType = "code.locked"
+	// EventCodeExpired verification code expired or not generated on verify
+	EventCodeExpired EventType = "code.expired"
+	// EventLimiterExceeded rate limiter hit rejected
+	EventLimiterExceeded EventType = "limiter.exceeded"
+	// EventLimiterLocked rate limiter locked
+	EventLimiterLocked EventType = "limiter.locked"
+	// EventLimiterReset rate limiter reset
+	EventLimiterReset EventType = "limiter.reset"
+)
+
+// Event security event passed to observers
+type Event struct {
+	Type EventType
+	Key  string
+	Time time.Time
+	// Success event outcome
+	Success bool
+	// Remaining verify attempts or rate limiter retries left
+	Remaining uint32
+}
+
+// Observer interface for security event observers (audit log, fraud detection)
+// observers called synchronously and must not block
+type Observer interface {
+	// Notify handle event
+	Notify(event Event)
+}
+
+// ObserverFunc adapter to use function as observer
+type ObserverFunc func(event Event)
+
+// Notify call f(event)
+func (f ObserverFunc) Notify(event Event) {
+	f(event)
+}
+
+// notify pass event to observer, do nothing if observer is nil
+func notify(observer Observer, eventType EventType, key string, at time.Time, success bool, remaining uint32) {
+	if observer == nil {
+		return
+	}
+	observer.Notify(Event{
+		Type:      eventType,
+		Key:       key,
+		Time:      at,
+		Success:   success,
+		Remaining: remaining,
+	})
+}
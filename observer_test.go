@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// eventRecorder observer that record event types
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) Notify(event Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []EventType {
+	types := make([]EventType, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestObserverPerInstance(t *testing.T) {
+	t.Parallel()
+	cache := NewFileCache("test", t.TempDir())
+	codes, limiters := new(eventRecorder), new(eventRecorder)
+
+	vc, err := NewVerificationCodeWithOptions("code", time.Minute, cache, VerificationOptions{
+		Secret:   []byte("secret"),
+		Observer: codes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := vc.Generate()
+	vc.Verify("wrong")
+	vc.Verify(code)
+	vc.Verify(code)
+
+	limiter := NewRateLimiterWithOptions("limiter", 1, time.Minute, cache, RateLimiterOptions{Observer: limiters})
+	limiter.Hit()
+	limiter.Hit()
+	limiter.Lock()
+	limiter.Reset()
+
+	// instance without observer must not notify others
+	NewRateLimiter("other", 0, time.Minute, cache).Hit()
+
+	expected := []EventType{EventCodeGenerated, EventCodeFailed, EventCodeVerified, EventCodeExpired}
+	if got := codes.types(); !equalEventTypes(got, expected) {
+		t.Fatalf("code events %v", got)
+	}
+	if codes.events[1].Remaining != DefaultVerifyAttempts-1 {
+		t.Fatalf("failed event remaining %d", codes.events[1].Remaining)
+	}
+	expected = []EventType{EventLimiterExceeded, EventLimiterLocked, EventLimiterReset}
+	if got := limiters.types(); !equalEventTypes(got, expected) {
+		t.Fatalf("limiter events %v", got)
+	}
+}
+
+func equalEventTypes(a []EventType, b []EventType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
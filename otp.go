@@ -0,0 +1,38 @@
+package cache
+
+import "time"
+
+// OTPAlgorithm one-time password hash algorithm
+type OTPAlgorithm string
+
+const (
+	// SHA1 hmac-sha1 algorithm
+	SHA1 OTPAlgorithm = "SHA1"
+	// SHA256 hmac-sha256 algorithm
+	SHA256 OTPAlgorithm = "SHA256"
+	// SHA512 hmac-sha512 algorithm
+	SHA512 OTPAlgorithm = "SHA512"
+)
+
+// OTPOptions one-time password options
+type OTPOptions struct {
+	// Digits code length from 6 to 10, 6 used if zero
+	Digits uint
+	// Period totp time step with second precision, 30 second used if less than second
+	Period time.Duration
+	// Algorithm hash algorithm, SHA1 used if empty
+	Algorithm OTPAlgorithm
+	// Skew accepted time steps before and after current step for totp
+	// and look-ahead counters for hotp
+	Skew uint
+}
+
+// OTP interface for rfc 4226 hotp and rfc 6238 totp one-time passwords
+type OTP interface {
+	// Generate generate current code (next expected counter code for hotp)
+	Generate() (string, error)
+	// Verify check code and remember accepted step or counter to prevent replay
+	Verify(code string) (bool, error)
+	// URI get otpauth uri for authenticator apps
+	URI(issuer string, account string) string
+}
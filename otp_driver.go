@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GenerateOTPSecret generate a random 20 byte otp secret
+func GenerateOTPSecret() ([]byte, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// HOTPCode generate rfc 4226 code for counter
+// digits must be from 6 to 10, truncated value never longer than 10 digits
+func HOTPCode(secret []byte, counter uint64, digits uint, algorithm OTPAlgorithm) string {
+	var h func() hash.Hash
+	switch algorithm {
+	case SHA256:
+		h = sha256.New
+	case SHA512:
+		h = sha512.New
+	default:
+		h = sha1.New
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(h, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	mod := int64(1)
+	for i := uint(0); i < digits && i < 10; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// otpDriver one-time password driver
+type otpDriver struct {
+	Key     string
+	Secret  []byte
+	Options OTPOptions
+	Cache   Cache
+	counter bool
+	clock   func() time.Time
+}
+
+func (otp *otpDriver) init(key string, secret []byte, options OTPOptions, cache Cache, counter bool) error {
+	if options.Digits == 0 {
+		options.Digits = 6
+	}
+	if options.Digits < 6 || options.Digits > 10 {
+		return errors.New("cache: otp digits must be from 6 to 10")
+	}
+	if options.Period < time.Second {
+		options.Period = 30 * time.Second
+	}
+	if options.Algorithm == "" {
+		options.Algorithm = SHA1
+	}
+	otp.Key = key
+	otp.Secret = secret
+	otp.Options = options
+	otp.Cache = cache
+	otp.counter = counter
+	otp.clock = time.Now
+	return nil
+}
+
+// stateKey get last accepted totp step or next hotp counter key
+func (otp *otpDriver) stateKey() string {
+	if otp.counter {
+		return otp.Key + "-counter"
+	}
+	return otp.Key + "-step"
+}
+
+// step get current totp time step
+func (otp *otpDriver) step() uint64 {
+	return uint64(otp.clock().Unix()) / uint64(otp.Options.Period/time.Second)
+}
+
+// code generate code for counter or time step
+func (otp *otpDriver) code(counter uint64) string {
+	return HOTPCode(otp.Secret, counter, otp.Options.Digits, otp.Options.Algorithm)
+}
+
+// match check if code match counter in constant time
+func (otp *otpDriver) match(code string, counter uint64) bool {
+	return subtle.ConstantTimeCompare([]byte(code), []byte(otp.code(counter))) == 1
+}
+
+// Generate generate current code (next expected counter code for hotp)
+func (otp *otpDriver) Generate() (string, error) {
+	if otp.counter {
+		return otp.code(otp.Cache.UInt64(otp.stateKey(), 0)), nil
+	}
+	return otp.code(otp.step()), nil
+}
+
+// Verify check code and remember accepted step or counter to prevent replay
+func (otp *otpDriver) Verify(code string) (bool, error) {
+	valid := false
+	err := synchronize(otp.Cache, otp.Key, func() {
+		if otp.counter {
+			counter := otp.Cache.UInt64(otp.stateKey(), 0)
+			for i := uint64(0); i <= uint64(otp.Options.Skew); i++ {
+				if otp.match(code, counter+i) {
+					valid = otp.Cache.PutForever(otp.stateKey(), counter+i+1)
+					return
+				}
+			}
+			return
+		}
+
+		step := otp.step()
+		last, accepted := otp.Cache.UInt64(otp.stateKey(), 0), otp.Cache.Exists(otp.stateKey())
+		skew := uint64(otp.Options.Skew)
+		for s := step - skew; s <= step+skew; s++ {
+			if accepted && s <= last {
+				continue
+			}
+			if otp.match(code, s) {
+				// remember step until all steps accepted by skew passed
+				valid = otp.Cache.Put(otp.stateKey(), s, time.Duration(2*skew+1)*otp.Options.Period)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	return valid, nil
+}
+
+// URI get otpauth uri for authenticator apps
+func (otp *otpDriver) URI(issuer string, account string) string {
+	label := account
+	if issuer != "" {
+		label = issuer + ":" + account
+	}
+	params := url.Values{}
+	params.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(otp.Secret))
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", string(otp.Options.Algorithm))
+	params.Set("digits", strconv.FormatUint(uint64(otp.Options.Digits), 10))
+
+	kind := "totp"
+	if otp.counter {
+		kind = "hotp"
+		params.Set("counter", strconv.FormatUint(otp.Cache.UInt64(otp.stateKey(), 0), 10))
+	} else {
+		params.Set("period", strconv.FormatInt(int64(otp.Options.Period/time.Second), 10))
+	}
+	return "otpauth://" + kind + "/" + url.PathEscape(label) + "?" + params.Encode()
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHOTPCodeRFC4226(t *testing.T) {
+	// rfc 4226 appendix d test values
+	secret := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expected {
+		if res := HOTPCode(secret, uint64(counter), 6, SHA1); res != code {
+			t.Errorf("counter %d: got %s, expected %s", counter, res, code)
+		}
+	}
+}
+
+func TestTOTPRFC6238(t *testing.T) {
+	// rfc 6238 appendix b test values
+	secrets := map[OTPAlgorithm]string{
+		SHA1:   "12345678901234567890",
+		SHA256: "12345678901234567890123456789012",
+		SHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		at    int64
+		codes map[OTPAlgorithm]string
+	}{
+		{59, map[OTPAlgorithm]string{SHA1: "94287082", SHA256: "46119246", SHA512: "90693936"}},
+		{1111111109, map[OTPAlgorithm]string{SHA1: "07081804", SHA256: "68084774", SHA512: "25091201"}},
+		{1111111111, map[OTPAlgorithm]string{SHA1: "14050471", SHA256: "67062674", SHA512: "99943326"}},
+		{1234567890, map[OTPAlgorithm]string{SHA1: "89005924", SHA256: "91819424", SHA512: "93441116"}},
+		{2000000000, map[OTPAlgorithm]string{SHA1: "69279037", SHA256: "90698825", SHA512: "38618901"}},
+		{20000000000, map[OTPAlgorithm]string{SHA1: "65353130", SHA256: "77737706", SHA512: "47863826"}},
+	}
+	cache := NewFileCache("test", t.TempDir())
+	for _, test := range tests {
+		for algorithm, code := range test.codes {
+			otp := NewTOTP("totp", []byte(secrets[algorithm]), OTPOptions{Digits: 8, Algorithm: algorithm}, cache).(*otpDriver)
+			at := time.Unix(test.at, 0)
+			otp.clock = func() time.Time { return at }
+			if res, _ := otp.Generate(); res != code {
+				t.Errorf("%s at %d: got %s, expected %s", algorithm, test.at, res, code)
+			}
+		}
+	}
+}
+
+func TestOTPDigits(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	for _, digits := range []uint{1, 5, 11, 19, 64} {
+		if NewTOTP("totp", []byte("secret"), OTPOptions{Digits: digits}, cache) != nil || NewHOTP("hotp", []byte("secret"), OTPOptions{Digits: digits}, cache) != nil {
+			t.Fatalf("%d digits accepted", digits)
+		}
+	}
+	for _, digits := range []uint{0, 6, 10} {
+		otp := NewHOTP("hotp", []byte("secret"), OTPOptions{Digits: digits}, cache)
+		if otp == nil {
+			t.Fatalf("%d digits rejected", digits)
+		}
+		if code, _ := otp.Generate(); digits > 0 && uint(len(code)) != digits {
+			t.Fatalf("code %s not %d digits", code, digits)
+		}
+	}
+}
+
+func TestHOTPReplay(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			secret := []byte("12345678901234567890")
+			otp := NewHOTP("hotp", secret, OTPOptions{Skew: 2}, cache)
+			defer cache.Forget("hotp-counter")
+
+			if ok, err := otp.Verify("755224"); err != nil || !ok {
+				t.Fatal("counter 0 code rejected")
+			}
+			if ok, _ := otp.Verify("755224"); ok {
+				t.Fatal("counter 0 code replayed")
+			}
+			if ok, _ := otp.Verify("969429"); !ok {
+				t.Fatal("counter 3 code in look-ahead rejected")
+			}
+			if ok, _ := otp.Verify("359152"); ok {
+				t.Fatal("skipped counter 2 code accepted")
+			}
+			if ok, _ := otp.Verify("162583"); ok {
+				t.Fatal("counter 7 code out of look-ahead accepted")
+			}
+			if code, _ := otp.Generate(); code != "338314" {
+				t.Fatalf("next code %s not counter 4 code", code)
+			}
+		})
+	}
+}
+
+func TestTOTPSkewReplay(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			secret := []byte("12345678901234567890")
+			otp := NewTOTP("totp", secret, OTPOptions{Skew: 1}, cache).(*otpDriver)
+			clock := newTestClock()
+			otp.clock = clock.Now
+			defer cache.Forget("totp-step")
+
+			step := otp.step()
+			code := func(s uint64) string {
+				return HOTPCode(secret, s, 6, SHA1)
+			}
+			if ok, _ := otp.Verify(code(step - 2)); ok {
+				t.Fatal("code out of skew accepted")
+			}
+			if ok, _ := otp.Verify(code(step - 1)); !ok {
+				t.Fatal("previous step code in skew rejected")
+			}
+			if ok, _ := otp.Verify(code(step - 1)); ok {
+				t.Fatal("previous step code replayed")
+			}
+			if ok, _ := otp.Verify(code(step + 1)); !ok {
+				t.Fatal("next step code in skew rejected")
+			}
+			if ok, _ := otp.Verify(code(step)); ok {
+				t.Fatal("code before accepted step accepted")
+			}
+
+			clock.Advance(2 * otp.Options.Period)
+			if ok, _ := otp.Verify(code(step + 2)); !ok {
+				t.Fatal("current code rejected after clock advanced")
+			}
+		})
+	}
+}
+
+func TestOTPURI(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	secret := []byte("12345678901234567890")
+
+	totp := NewTOTP("totp", secret, OTPOptions{Digits: 8, Period: time.Minute, Algorithm: SHA256}, cache)
+	u, err := url.Parse(totp.URI("Acme Co", "alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Acme Co:alice@example.com" {
+		t.Fatalf("totp uri %s", u)
+	}
+	query := u.Query()
+	expected := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Acme Co",
+		"algorithm": "SHA256",
+		"digits":    "8",
+		"period":    "60",
+		"counter":   "",
+	}
+	for param, value := range expected {
+		if query.Get(param) != value {
+			t.Errorf("totp uri %s param %q, expected %q", param, query.Get(param), value)
+		}
+	}
+
+	hotp := NewHOTP("hotp", secret, OTPOptions{}, cache)
+	hotp.Verify("755224")
+	u, _ = url.Parse(hotp.URI("", "bob"))
+	if u.Host != "hotp" || u.Path != "/bob" || u.Query().Get("counter") != "1" || u.Query().Get("issuer") != "" || u.Query().Get("period") != "" {
+		t.Fatalf("hotp uri %s", u)
+	}
+}
@@ -0,0 +1,40 @@
+package cache
+
+import "time"
+
+// Period calendar period of quota
+type Period int
+
+const (
+	// Hourly period reset at start of each hour
+	Hourly Period = iota
+	// Daily period reset at midnight
+	Daily
+	// Weekly period reset at monday midnight
+	Weekly
+	// Monthly period reset at first day of month midnight
+	Monthly
+	// Yearly period reset at first day of year midnight
+	Yearly
+)
+
+// QuotaOptions quota options
+type QuotaOptions struct {
+	// Clock time source deciding current period, time.Now used if nil
+	Clock func() time.Time
+}
+
+// Quota interface for quota counter with calendar aligned periods
+type Quota interface {
+	// Consume use n units of quota
+	// return false and consume nothing if quota exceeded
+	Consume(n uint32) bool
+	// Refund give back n units of quota
+	Refund(n uint32) bool
+	// Used get used units in current period
+	Used() uint32
+	// Remaining get remaining units in current period
+	Remaining() uint32
+	// ResetsAt get current period end
+	ResetsAt() time.Time
+}
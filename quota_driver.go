@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"time"
+)
+
+// quotaDriver quota driver
+// each period counted in separate key expired at period end
+type quotaDriver struct {
+	Key      string
+	Limit    uint32
+	Period   Period
+	Location *time.Location
+	Cache    Cache
+
+	clock func() time.Time
+}
+
+func (quota *quotaDriver) init(key string, limit uint32, period Period, location *time.Location, cache Cache, options QuotaOptions) {
+	if location == nil {
+		location = time.UTC
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	quota.Key = key
+	quota.Limit = limit
+	quota.Period = period
+	quota.Location = location
+	quota.Cache = cache
+	quota.clock = options.Clock
+}
+
+// bounds get period start and end of time
+func (quota *quotaDriver) bounds(now time.Time) (time.Time, time.Time) {
+	now = now.In(quota.Location)
+	y, m, d := now.Date()
+	switch quota.Period {
+	case Hourly:
+		start := time.Date(y, m, d, now.Hour(), 0, 0, 0, quota.Location)
+		return start, start.Add(time.Hour)
+	case Weekly:
+		// week start at monday
+		start := time.Date(y, m, d-(int(now.Weekday())+6)%7, 0, 0, 0, 0, quota.Location)
+		return start, start.AddDate(0, 0, 7)
+	case Monthly:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, quota.Location)
+		return start, start.AddDate(0, 1, 0)
+	case Yearly:
+		start := time.Date(y, 1, 1, 0, 0, 0, 0, quota.Location)
+		return start, start.AddDate(1, 0, 0)
+	default:
+		start := time.Date(y, m, d, 0, 0, 0, 0, quota.Location)
+		return start, start.AddDate(0, 0, 1)
+	}
+}
+
+// periodKey get current period counter key and time left until period end
+func (quota *quotaDriver) periodKey() (string, time.Duration) {
+	now := quota.clock()
+	start, end := quota.bounds(now)
+	return quota.Key + "-" + start.UTC().Format("20060102150405"), end.Sub(now)
+}
+
+// Consume use n units of quota
+// period expiration refreshed after increment, units counted even if refresh fails
+func (quota *quotaDriver) Consume(n uint32) bool {
+	key, ttl := quota.periodKey()
+	consumed := false
+	synchronize(quota.Cache, key, func() {
+		used := quota.Cache.Int64(key, 0)
+		if used+int64(n) > int64(quota.Limit) {
+			return
+		}
+		if used > 0 && quota.Cache.IncrementBy(key, n) {
+			consumed = true
+			quota.Cache.Touch(key, ttl)
+		} else {
+			consumed = quota.Cache.Put(key, n, ttl)
+		}
+	})
+	return consumed
+}
+
+// Refund give back n units of quota
+func (quota *quotaDriver) Refund(n uint32) bool {
+	key, _ := quota.periodKey()
+	refunded := false
+	synchronize(quota.Cache, key, func() {
+		used := quota.Cache.Int64(key, 0)
+		if used <= 0 {
+			return
+		}
+		if int64(n) > used {
+			n = uint32(used)
+		}
+		refunded = quota.Cache.DecrementBy(key, n)
+	})
+	return refunded
+}
+
+// Used get used units in current period
+func (quota *quotaDriver) Used() uint32 {
+	key, _ := quota.periodKey()
+	used := quota.Cache.Int64(key, 0)
+	if used < 0 {
+		return 0
+	}
+	return uint32(used)
+}
+
+// Remaining get remaining units in current period
+func (quota *quotaDriver) Remaining() uint32 {
+	if used := quota.Used(); used < quota.Limit {
+		return quota.Limit - used
+	}
+	return 0
+}
+
+// ResetsAt get current period end
+func (quota *quotaDriver) ResetsAt() time.Time {
+	_, end := quota.bounds(quota.clock())
+	return end
+}
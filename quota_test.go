@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestQuota create quota driven by test clock at time
+func newTestQuota(t *testing.T, period Period, location *time.Location, at time.Time) (Quota, *testClock) {
+	t.Helper()
+	clock := &testClock{now: at}
+	cache := NewFileCache("test", t.TempDir())
+	return NewQuotaWithOptions("quota", 5, period, location, cache, QuotaOptions{Clock: clock.Now}), clock
+}
+
+func TestQuotaBoundaries(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	tests := []struct {
+		name     string
+		period   Period
+		location *time.Location
+		at       time.Time
+		end      time.Time
+	}{
+		{"weekly sunday", Weekly, time.UTC, time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"weekly monday", Weekly, time.UTC, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"weekly wednesday", Weekly, time.UTC, time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"monthly january end", Monthly, time.UTC, time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"monthly leap day", Monthly, time.UTC, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", Yearly, time.UTC, time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"daily spring dst", Daily, berlin, time.Date(2024, 3, 31, 1, 0, 0, 0, berlin), time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)},
+		{"daily autumn dst", Daily, berlin, time.Date(2024, 10, 27, 12, 0, 0, 0, berlin), time.Date(2024, 10, 28, 0, 0, 0, 0, berlin)},
+		{"hourly after spring dst", Hourly, berlin, time.Date(2024, 3, 31, 3, 30, 0, 0, berlin), time.Date(2024, 3, 31, 4, 0, 0, 0, berlin)},
+		{"weekly over dst", Weekly, berlin, time.Date(2024, 3, 31, 23, 0, 0, 0, berlin), time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)},
+	}
+	for _, test := range tests {
+		quota, _ := newTestQuota(t, test.period, test.location, test.at)
+		if end := quota.ResetsAt(); !end.Equal(test.end) {
+			t.Errorf("%s: resets at %s, expected %s", test.name, end, test.end)
+		}
+	}
+
+	// daylight saving days last 23 and 25 hours
+	spring, _ := newTestQuota(t, Daily, berlin, time.Date(2024, 3, 31, 12, 0, 0, 0, berlin))
+	if d := spring.ResetsAt().Sub(time.Date(2024, 3, 31, 0, 0, 0, 0, berlin)); d != 23*time.Hour {
+		t.Errorf("spring dst day lasts %s", d)
+	}
+	autumn, _ := newTestQuota(t, Daily, berlin, time.Date(2024, 10, 27, 12, 0, 0, 0, berlin))
+	if d := autumn.ResetsAt().Sub(time.Date(2024, 10, 27, 0, 0, 0, 0, berlin)); d != 25*time.Hour {
+		t.Errorf("autumn dst day lasts %s", d)
+	}
+}
+
+func TestQuotaPeriodRollover(t *testing.T) {
+	quota, clock := newTestQuota(t, Monthly, time.UTC, time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC))
+	if !quota.Consume(5) || quota.Consume(1) || quota.Remaining() != 0 {
+		t.Fatal("quota limit not enforced")
+	}
+	clock.Advance(time.Minute)
+	if quota.Used() != 0 || quota.Remaining() != 5 || !quota.ResetsAt().Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("quota not reset at month end")
+	}
+	if !quota.Consume(2) || quota.Used() != 2 {
+		t.Fatal("new period quota not consumed")
+	}
+}
+
+func TestQuotaRefund(t *testing.T) {
+	quota, _ := newTestQuota(t, Daily, time.UTC, time.Now())
+	if quota.Refund(1) {
+		t.Fatal("refund without usage")
+	}
+	if !quota.Consume(3) || !quota.Consume(2) || quota.Consume(1) {
+		t.Fatal("quota limit not enforced")
+	}
+	if !quota.Refund(2) || quota.Used() != 3 || !quota.Consume(2) {
+		t.Fatal("refunded units not available")
+	}
+	if !quota.Refund(10) || quota.Used() != 0 || quota.Remaining() != 5 {
+		t.Fatal("refund over usage not capped")
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"time"
+)
+
+// DefaultBackoffSchedule default lockout durations of backoff limiter
+var DefaultBackoffSchedule = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 24 * time.Hour}
+
+// backoffLimiter rate limiter with lockout lengthened on each strike
+// strikes decay after quiet period passed since last lockout ended
+type backoffLimiter struct {
+	Key      string
+	Schedule []time.Duration
+	Decay    time.Duration
+	Cache    Cache
+	attempts *rateLimiterDriver
+}
+
+func (limiter *backoffLimiter) init(key string, maxAttempts uint32, window time.Duration, schedule []time.Duration, decay time.Duration, cache Cache, options RateLimiterOptions) {
+	if len(schedule) == 0 {
+		schedule = DefaultBackoffSchedule
+	}
+	limiter.Key = key
+	limiter.Schedule = schedule
+	limiter.Decay = decay
+	limiter.Cache = cache
+	limiter.attempts = new(rateLimiterDriver)
+	limiter.attempts.init(key+"-attempts", maxAttempts, window, cache, options)
+}
+
+func (limiter *backoffLimiter) lockoutKey() string {
+	return limiter.Key + "-lockout"
+}
+
+func (limiter *backoffLimiter) strikesKey() string {
+	return limiter.Key + "-strikes"
+}
+
+// penalty get lockout duration of strike level
+func (limiter *backoffLimiter) penalty(level int) time.Duration {
+	if level > len(limiter.Schedule) {
+		level = len(limiter.Schedule)
+	}
+	return limiter.Schedule[level-1]
+}
+
+// strike escalate strike level and start lockout
+// return attempts window remaining time if lockout not started
+func (limiter *backoffLimiter) strike() time.Duration {
+	var penalty time.Duration
+	struck := false
+	if err := synchronize(limiter.Cache, limiter.Key, func() {
+		if ttl := limiter.Cache.TTL(limiter.lockoutKey()); ttl > 0 {
+			penalty = ttl
+			return
+		}
+		level := limiter.Cache.Int(limiter.strikesKey(), 0) + 1
+		penalty = limiter.penalty(level)
+		limiter.Cache.Put(limiter.lockoutKey(), level, penalty)
+		limiter.Cache.Put(limiter.strikesKey(), level, penalty+limiter.Decay)
+		limiter.attempts.reset()
+		struck = true
+	}); err != nil {
+		return limiter.attempts.AvailableIn()
+	}
+	if struck {
+		notify(limiter.attempts.observer, EventLimiterLocked, limiter.Key, limiter.attempts.clock(), true, 0)
+	}
+	return penalty
+}
+
+// locked get hit result for locked out limiter
+func (limiter *backoffLimiter) locked(ttl time.Duration) Result {
+	return Result{
+		Limit:      limiter.attempts.Max,
+		ResetIn:    ttl,
+		RetryAfter: ttl,
+	}
+}
+
+// Hit decrease the allowed times and return hit result atomically
+// lockout started when hit rejected after all allowed attempts used
+func (limiter *backoffLimiter) Hit() Result {
+	if ttl := limiter.AvailableIn(); ttl > 0 {
+		return limiter.locked(ttl)
+	}
+
+	res := limiter.attempts.Hit()
+	if !res.Allowed && res.Err == nil {
+		return limiter.locked(limiter.strike())
+	}
+	return res
+}
+
+// Lock lock rate limiter and escalate strike level
+func (limiter *backoffLimiter) Lock() {
+	limiter.strike()
+}
+
+// Reset reset rate limiter and strike level
+func (limiter *backoffLimiter) Reset() {
+	limiter.attempts.Reset()
+	limiter.Cache.Forget(limiter.lockoutKey())
+	limiter.Cache.Forget(limiter.strikesKey())
+}
+
+// MustLock check if rate limiter must lock access
+func (limiter *backoffLimiter) MustLock() bool {
+	return limiter.Cache.Exists(limiter.lockoutKey())
+}
+
+// TotalAttempts get user attempts count
+func (limiter *backoffLimiter) TotalAttempts() uint32 {
+	if limiter.MustLock() {
+		return limiter.attempts.Max
+	}
+	return limiter.attempts.TotalAttempts()
+}
+
+// RetriesLeft get user retries left
+func (limiter *backoffLimiter) RetriesLeft() uint32 {
+	return limiter.attempts.Max - limiter.TotalAttempts()
+}
+
+// AvailableIn get time until unlock
+func (limiter *backoffLimiter) AvailableIn() time.Duration {
+	return limiter.Cache.TTL(limiter.lockoutKey())
+}
+
+// Strikes get lockout escalation level
+func (limiter *backoffLimiter) Strikes() uint32 {
+	return limiter.Cache.UInt32(limiter.strikesKey(), 0)
+}
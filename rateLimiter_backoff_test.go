@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffStrikeOnRejectedHit(t *testing.T) {
+	var events []Event
+	options := RateLimiterOptions{
+		Observer: ObserverFunc(func(event Event) {
+			events = append(events, event)
+		}),
+	}
+
+	cache := NewFileCache("test", t.TempDir())
+	schedule := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond}
+	limiter := NewBackoffLimiterWithOptions("login", 2, time.Minute, schedule, time.Minute, cache, options)
+	for i := 0; i < 2; i++ {
+		if res := limiter.Hit(); !res.Allowed {
+			t.Fatalf("hit %d rejected", i+1)
+		}
+	}
+	if limiter.MustLock() || limiter.Strikes() != 0 {
+		t.Fatal("last allowed hit started lockout")
+	}
+
+	if res := limiter.Hit(); res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 100*time.Millisecond {
+		t.Fatalf("exceeded hit %+v", res)
+	}
+	if !limiter.MustLock() || limiter.Strikes() != 1 {
+		t.Fatal("rejected hit not started lockout")
+	}
+	for _, event := range events {
+		if event.Type == EventLimiterReset {
+			t.Fatal("lockout reported as reset")
+		}
+	}
+	if last := events[len(events)-1]; last.Type != EventLimiterLocked || last.Key != "login" {
+		t.Fatalf("lockout not reported %+v", last)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if limiter.MustLock() || limiter.RetriesLeft() != 2 {
+		t.Fatal("lockout not expired")
+	}
+	limiter.Hit()
+	limiter.Hit()
+	limiter.Hit()
+	if limiter.Strikes() != 2 || limiter.AvailableIn() <= 100*time.Millisecond {
+		t.Fatal("lockout not escalated")
+	}
+}
@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var redisCompositeHitScript = redis.NewScript(-1, `
+local denied = 0
+local wait = -1
+local states = {}
+for i = 1, #KEYS do
+	local used = tonumber(redis.call("GET", KEYS[i])) or 0
+	local ttl = redis.call("PTTL", KEYS[i])
+	if ttl < 0 then
+		ttl = 0
+	end
+	if used >= tonumber(ARGV[2 * i - 1]) and ttl > wait then
+		denied = i
+		wait = ttl
+	end
+	states[i] = {used, ttl}
+end
+if denied == 0 then
+	for i = 1, #KEYS do
+		local used = redis.call("INCR", KEYS[i])
+		if used == 1 then
+			redis.call("PEXPIRE", KEYS[i], ARGV[2 * i])
+		end
+		states[i] = {used, redis.call("PTTL", KEYS[i])}
+	end
+end
+local result = {denied}
+for i = 1, #KEYS do
+	table.insert(result, states[i][1])
+	table.insert(result, states[i][2])
+end
+return result`)
+
+var redisCompositeLockScript = redis.NewScript(-1, `
+for i = 1, #KEYS do
+	local ttl = redis.call("PTTL", KEYS[i])
+	if ttl <= 0 then
+		ttl = ARGV[2 * i]
+	end
+	redis.call("SET", KEYS[i], ARGV[2 * i - 1], "PX", ttl)
+end
+return 1`)
+
+// compositeLimiter rate limiter combining multiple fixed window limits
+// each limit store used hits count in separate key
+type compositeLimiter struct {
+	Key    string
+	Limits []Limit
+	Cache  Cache
+}
+
+func (limiter *compositeLimiter) init(key string, cache Cache, limits []Limit) error {
+	limiter.Key = key
+	limiter.Cache = cache
+	limiter.Limits = make([]Limit, len(limits))
+	names := make(map[string]bool, len(limits))
+	for i, limit := range limits {
+		if limit.Name == "" {
+			limit.Name = strconv.Itoa(i)
+		}
+		if names[limit.Name] {
+			return errors.New("cache: duplicate composite limit name " + limit.Name)
+		}
+		names[limit.Name] = true
+		limiter.Limits[i] = limit
+	}
+	return nil
+}
+
+// key get limit cache key
+func (limiter *compositeLimiter) key(limit Limit) string {
+	return limiter.Key + "-" + limit.Name
+}
+
+// args get limits max and window script arguments
+func (limiter *compositeLimiter) args() ([]string, []interface{}) {
+	keys := make([]string, 0, len(limiter.Limits))
+	args := make([]interface{}, 0, 2*len(limiter.Limits))
+	for _, limit := range limiter.Limits {
+		keys = append(keys, limiter.key(limit))
+		args = append(args, limit.Max, milliseconds(limit.Window))
+	}
+	return keys, args
+}
+
+// unavailable create hit result for failed state access with lowest limit max
+func (limiter *compositeLimiter) unavailable(err error) CompositeResult {
+	var max uint32
+	for i, limit := range limiter.Limits {
+		if i == 0 || limit.Max < max {
+			max = limit.Max
+		}
+	}
+	return CompositeResult{Result: unavailable(max, err)}
+}
+
+// states get used hits and ttl of limits
+func (limiter *compositeLimiter) states() ([]int64, []time.Duration) {
+	used := make([]int64, len(limiter.Limits))
+	ttls := make([]time.Duration, len(limiter.Limits))
+	for i, limit := range limiter.Limits {
+		used[i] = limiter.Cache.Int64(limiter.key(limit), 0)
+		ttls[i] = limiter.Cache.TTL(limiter.key(limit))
+	}
+	return used, ttls
+}
+
+// denied get 1 based index of exhausted limit with longest wait, zero if no limit exhausted
+func (limiter *compositeLimiter) denied(used []int64, ttls []time.Duration) int {
+	denied := 0
+	for i, limit := range limiter.Limits {
+		if used[i] >= int64(limit.Max) && (denied == 0 || ttls[i] > ttls[denied-1]) {
+			denied = i + 1
+		}
+	}
+	return denied
+}
+
+// restrictive get index of limit with least remaining hits
+func (limiter *compositeLimiter) restrictive(used []int64) (int, uint32) {
+	index := -1
+	var remaining uint32
+	for i, limit := range limiter.Limits {
+		left := int64(limit.Max) - used[i]
+		if left < 0 {
+			left = 0
+		}
+		if index < 0 || uint32(left) < remaining {
+			index = i
+			remaining = uint32(left)
+		}
+	}
+	return index, remaining
+}
+
+// result create hit result from limits state
+func (limiter *compositeLimiter) result(denied int, used []int64, ttls []time.Duration) CompositeResult {
+	if denied > 0 {
+		return CompositeResult{
+			Result: Result{
+				Limit:      limiter.Limits[denied-1].Max,
+				ResetIn:    ttls[denied-1],
+				RetryAfter: ttls[denied-1],
+			},
+			Triggered: limiter.Limits[denied-1].Name,
+		}
+	}
+
+	res := CompositeResult{Result: Result{Allowed: true}}
+	if index, remaining := limiter.restrictive(used); index >= 0 {
+		res.Limit = limiter.Limits[index].Max
+		res.Remaining = remaining
+		res.ResetIn = ttls[index]
+	}
+	return res
+}
+
+// HitAll hit all limits atomically and return hit result with exhausted limit name
+func (limiter *compositeLimiter) HitAll() CompositeResult {
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		keys, args := limiter.args()
+		reply, err := redis.Int64s(rc.eval(redisCompositeHitScript, keys, args...))
+		if err != nil || len(reply) != 1+2*len(limiter.Limits) {
+			return limiter.unavailable(err)
+		}
+		used := make([]int64, len(limiter.Limits))
+		ttls := make([]time.Duration, len(limiter.Limits))
+		for i := range limiter.Limits {
+			used[i] = reply[1+2*i]
+			ttls[i] = time.Duration(reply[2+2*i]) * time.Millisecond
+		}
+		return limiter.result(int(reply[0]), used, ttls)
+	}
+
+	res := limiter.unavailable(nil)
+	err := synchronize(limiter.Cache, limiter.Key, func() {
+		used, ttls := limiter.states()
+		if denied := limiter.denied(used, ttls); denied > 0 {
+			res = limiter.result(denied, used, ttls)
+			return
+		}
+		for i, limit := range limiter.Limits {
+			key := limiter.key(limit)
+			used[i]++
+			if used[i] > 1 && limiter.Cache.Set(key, used[i]) {
+				continue
+			}
+			used[i] = 1
+			ttls[i] = limit.Window
+			limiter.Cache.Put(key, used[i], limit.Window)
+		}
+		res = limiter.result(0, used, ttls)
+	})
+	if err != nil {
+		return limiter.unavailable(err)
+	}
+	return res
+}
+
+// Hit decrease the allowed times and return hit result atomically
+func (limiter *compositeLimiter) Hit() Result {
+	return limiter.HitAll().Result
+}
+
+// Lock lock rate limiter
+// exhaust all limits atomically, current windows kept
+func (limiter *compositeLimiter) Lock() {
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		keys, args := limiter.args()
+		rc.eval(redisCompositeLockScript, keys, args...)
+		return
+	}
+	synchronize(limiter.Cache, limiter.Key, func() {
+		for _, limit := range limiter.Limits {
+			key := limiter.key(limit)
+			if !limiter.Cache.Set(key, limit.Max) {
+				limiter.Cache.Put(key, limit.Max, limit.Window)
+			}
+		}
+	})
+}
+
+// Reset reset rate limiter
+func (limiter *compositeLimiter) Reset() {
+	for _, limit := range limiter.Limits {
+		limiter.Cache.Forget(limiter.key(limit))
+	}
+}
+
+// MustLock check if rate limiter must lock access
+func (limiter *compositeLimiter) MustLock() bool {
+	return limiter.denied(limiter.states()) > 0
+}
+
+// TotalAttempts get user attempts count of most restrictive limit
+func (limiter *compositeLimiter) TotalAttempts() uint32 {
+	used, _ := limiter.states()
+	index, remaining := limiter.restrictive(used)
+	if index < 0 {
+		return 0
+	}
+	return limiter.Limits[index].Max - remaining
+}
+
+// RetriesLeft get user retries left of most restrictive limit
+func (limiter *compositeLimiter) RetriesLeft() uint32 {
+	used, _ := limiter.states()
+	_, remaining := limiter.restrictive(used)
+	return remaining
+}
+
+// AvailableIn get time until unlock
+func (limiter *compositeLimiter) AvailableIn() time.Duration {
+	used, ttls := limiter.states()
+	if denied := limiter.denied(used, ttls); denied > 0 {
+		return ttls[denied-1]
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompositeDenyIfAny(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter := NewCompositeLimiter("composite", cache,
+				Limit{Name: "burst", Max: 2, Window: time.Minute},
+				Limit{Name: "hourly", Max: 3, Window: time.Hour},
+			)
+			defer limiter.Reset()
+			composite := limiter.(*compositeLimiter)
+			burst, hourly := composite.key(composite.Limits[0]), composite.key(composite.Limits[1])
+
+			for i := uint32(1); i <= 2; i++ {
+				if res := limiter.HitAll(); !res.Allowed || res.Limit != 2 || res.Remaining != 2-i || res.Triggered != "" {
+					t.Fatalf("hit %d %+v", i, res)
+				}
+			}
+			res := limiter.HitAll()
+			if res.Allowed || res.Triggered != "burst" || res.Limit != 2 || res.RetryAfter <= 0 || res.RetryAfter > time.Minute {
+				t.Fatalf("burst exhausted %+v", res)
+			}
+			if cache.Int64(burst, 0) != 2 || cache.Int64(hourly, 0) != 2 {
+				t.Fatal("rejected hit counted")
+			}
+
+			cache.Forget(burst)
+			if res := limiter.HitAll(); !res.Allowed || res.Limit != 3 || res.Remaining != 0 {
+				t.Fatalf("hit after burst window %+v", res)
+			}
+			res = limiter.HitAll()
+			if res.Allowed || res.Triggered != "hourly" || res.Limit != 3 || res.RetryAfter <= time.Minute {
+				t.Fatalf("hourly exhausted %+v", res)
+			}
+			if cache.Int64(burst, 0) != 1 || cache.Int64(hourly, 0) != 3 {
+				t.Fatal("rejected hit counted")
+			}
+			if !limiter.MustLock() || limiter.RetriesLeft() != 0 || limiter.AvailableIn() <= time.Minute {
+				t.Fatal("exhausted limiter not locked")
+			}
+		})
+	}
+}
+
+func TestCompositeLockReset(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter := NewCompositeLimiter("composite-lock", cache,
+				Limit{Max: 2, Window: time.Minute},
+				Limit{Max: 5, Window: time.Hour},
+			)
+			defer limiter.Reset()
+
+			limiter.Hit()
+			limiter.Lock()
+			if res := limiter.HitAll(); res.Allowed || res.Triggered != "1" {
+				t.Fatalf("hit on locked limiter %+v", res)
+			}
+			if !limiter.MustLock() || limiter.TotalAttempts() != 2 {
+				t.Fatal("lock not exhausted limits")
+			}
+			limiter.Reset()
+			if limiter.MustLock() || limiter.RetriesLeft() != 2 || !limiter.Hit().Allowed {
+				t.Fatal("reset not cleared limits")
+			}
+		})
+	}
+}
+
+func TestCompositeDuplicateName(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	if NewCompositeLimiter("composite", cache, Limit{Name: "a", Max: 1, Window: time.Second}, Limit{Name: "a", Max: 2, Window: time.Minute}) != nil {
+		t.Fatal("duplicate name accepted")
+	}
+	if NewCompositeLimiter("composite", cache, Limit{Name: "1", Max: 1, Window: time.Second}, Limit{Max: 2, Window: time.Minute}) != nil {
+		t.Fatal("name colliding with index accepted")
+	}
+}
+
+func TestCompositeUnavailable(t *testing.T) {
+	cache := plainCache{NewFileCache("test", t.TempDir())}
+	limiter := NewCompositeLimiter("composite", cache, Limit{Max: 5, Window: time.Hour}, Limit{Max: 2, Window: time.Minute})
+	if res := limiter.HitAll(); res.Allowed || res.Err != ErrLockNotSupported || res.Limit != 2 {
+		t.Fatalf("hit without lock %+v", res)
+	}
+}
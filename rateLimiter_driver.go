@@ -2,76 +2,221 @@ package cache
 
 import (
 	"time"
+
+	"github.com/gomodule/redigo/redis"
 )
 
+var redisRateLimiterHitScript = redis.NewScript(-1, `
+local now = tonumber(ARGV[3])
+local remaining = tonumber(redis.call("GET", KEYS[1]))
+local ends = tonumber(redis.call("GET", KEYS[2]))
+if remaining == nil or ends == nil or ends <= now then
+	local max = tonumber(ARGV[1])
+	if max <= 0 then
+		return {0, 0, 0}
+	end
+	redis.call("SET", KEYS[1], max - 1, "PX", ARGV[2])
+	redis.call("SET", KEYS[2], now + tonumber(ARGV[2]), "PX", ARGV[2])
+	return {1, max - 1, tonumber(ARGV[2])}
+end
+if remaining > 0 then
+	return {1, redis.call("DECR", KEYS[1]), ends - now}
+end
+return {0, 0, ends - now}`)
+
+var redisRateLimiterLockScript = redis.NewScript(-1, `
+local now = tonumber(ARGV[2])
+local ends = tonumber(redis.call("GET", KEYS[2]))
+if ends == nil or ends <= now or redis.call("EXISTS", KEYS[1]) == 0 then
+	redis.call("SET", KEYS[1], 0, "PX", ARGV[1])
+	redis.call("SET", KEYS[2], now + tonumber(ARGV[1]), "PX", ARGV[1])
+	return 1
+end
+redis.call("SET", KEYS[1], 0, "PX", ends - now)
+return 1`)
+
 // rateLimiterDriver rate limiter driver
+// window expiration decided by clock, cache ttl only clean up expired windows
 type rateLimiterDriver struct {
-	Key   string
-	Max   uint32
-	Cache Cache
+	Key      string
+	Max      uint32
+	TTL      time.Duration
+	Cache    Cache
+	clock    func() time.Time
+	observer Observer
 }
 
-func (limiter *rateLimiterDriver) init(key string, maxAttempts uint32, ttl time.Duration, cache Cache) {
+func (limiter *rateLimiterDriver) init(key string, maxAttempts uint32, ttl time.Duration, cache Cache, options RateLimiterOptions) {
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
 	limiter.Key = key
 	limiter.Max = maxAttempts
+	limiter.TTL = ttl
 	limiter.Cache = cache
-	if !cache.Exists(key) {
-		cache.Put(key, maxAttempts, ttl)
+	limiter.clock = options.Clock
+	limiter.observer = options.Observer
+}
+
+// windowKey get window end key
+func (limiter *rateLimiterDriver) windowKey() string {
+	return limiter.Key + "-window"
+}
+
+// window get current window end in unix milliseconds
+// return false if no window started or window expired
+func (limiter *rateLimiterDriver) window(now int64) (int64, bool) {
+	end := limiter.Cache.Int64(limiter.windowKey(), 0)
+	if end <= now || !limiter.Cache.Exists(limiter.Key) {
+		return 0, false
+	}
+	return end, true
+}
+
+// start start new window with remaining hits
+func (limiter *rateLimiterDriver) start(remaining uint32, now int64) bool {
+	return limiter.Cache.Put(limiter.Key, remaining, limiter.TTL) &&
+		limiter.Cache.Put(limiter.windowKey(), now+milliseconds(limiter.TTL), limiter.TTL)
+}
+
+// result create hit result
+func (limiter *rateLimiterDriver) result(allowed bool, remaining int64, ttl time.Duration) Result {
+	if remaining < 0 {
+		remaining = 0
+	}
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limiter.Max,
+		Remaining: uint32(remaining),
+		ResetIn:   ttl,
+	}
+	if !res.Allowed {
+		res.RetryAfter = ttl
+	}
+	return res
+}
+
+// unavailable create hit result for failed limiter state access
+func unavailable(limit uint32, err error) Result {
+	if err == nil {
+		err = ErrLimiterUnavailable
+	}
+	return Result{Limit: limit, Err: err}
+}
+
+// Hit decrease the allowed times and return hit result atomically
+// new window started on first hit after window expired
+func (limiter *rateLimiterDriver) Hit() Result {
+	res := limiter.hit()
+	if !res.Allowed && res.Err == nil {
+		notify(limiter.observer, EventLimiterExceeded, limiter.Key, limiter.clock(), false, 0)
 	}
+	return res
 }
 
-// Hit decrease the allowed times
-func (limiter *rateLimiterDriver) Hit() {
-	if limiter.Cache.Int(limiter.Key, 0) > 0 {
-		limiter.Cache.Decrement(limiter.Key)
+// hit decrease the allowed times and return hit result atomically
+func (limiter *rateLimiterDriver) hit() Result {
+	now := unixMilli(limiter.clock())
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		reply, err := redis.Int64s(rc.eval(redisRateLimiterHitScript, []string{limiter.Key, limiter.windowKey()}, limiter.Max, milliseconds(limiter.TTL), now))
+		if err != nil || len(reply) != 3 {
+			return unavailable(limiter.Max, err)
+		}
+		return limiter.result(reply[0] == 1, reply[1], time.Duration(reply[2])*time.Millisecond)
+	}
+
+	res := unavailable(limiter.Max, nil)
+	err := synchronize(limiter.Cache, limiter.Key, func() {
+		end, ok := limiter.window(now)
+		if !ok {
+			if limiter.Max == 0 {
+				res = limiter.result(false, 0, 0)
+			} else if limiter.start(limiter.Max-1, now) {
+				res = limiter.result(true, int64(limiter.Max-1), limiter.TTL)
+			}
+			return
+		}
+		ttl := time.Duration(end-now) * time.Millisecond
+		remaining := int64(limiter.Cache.Int(limiter.Key, 0))
+		if remaining <= 0 {
+			res = limiter.result(false, 0, ttl)
+		} else if limiter.Cache.Decrement(limiter.Key) {
+			res = limiter.result(true, remaining-1, ttl)
+		}
+	})
+	if err != nil {
+		return unavailable(limiter.Max, err)
 	}
+	return res
 }
 
 // Lock lock rate limiter
+// new locked window started if window expired
 func (limiter *rateLimiterDriver) Lock() {
-	if limiter.Cache.Exists(limiter.Key) {
-		limiter.Cache.Set(limiter.Key, 0)
+	now := unixMilli(limiter.clock())
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		rc.eval(redisRateLimiterLockScript, []string{limiter.Key, limiter.windowKey()}, milliseconds(limiter.TTL), now)
+	} else {
+		synchronize(limiter.Cache, limiter.Key, func() {
+			if _, ok := limiter.window(now); !ok || !limiter.Cache.Set(limiter.Key, 0) {
+				limiter.start(0, now)
+			}
+		})
 	}
+	notify(limiter.observer, EventLimiterLocked, limiter.Key, limiter.clock(), true, 0)
 }
 
 // Reset reset rate limiter
 func (limiter *rateLimiterDriver) Reset() {
+	limiter.reset()
+	notify(limiter.observer, EventLimiterReset, limiter.Key, limiter.clock(), true, limiter.Max)
+}
+
+// reset reset rate limiter without notifying observers
+func (limiter *rateLimiterDriver) reset() {
 	limiter.Cache.Forget(limiter.Key)
+	limiter.Cache.Forget(limiter.windowKey())
 }
 
 // MustLock check if rate limiter must lock access
 func (limiter *rateLimiterDriver) MustLock() bool {
-	return limiter.Cache.Exists(limiter.Key) && limiter.Cache.Int(limiter.Key, 0) <= 0
+	_, ok := limiter.window(unixMilli(limiter.clock()))
+	return ok && limiter.Cache.Int(limiter.Key, 0) <= 0
 }
 
 // TotalAttempts get user attempts count
 func (limiter *rateLimiterDriver) TotalAttempts() uint32 {
-	old := limiter.Cache.Int(limiter.Key, 0)
-	if old < 0 {
-		old = 0
+	if _, ok := limiter.window(unixMilli(limiter.clock())); !ok {
+		return 0
 	}
-	if limiter.Cache.Exists(limiter.Key) {
-		return limiter.Max - uint32(old)
+	remaining := limiter.Cache.Int(limiter.Key, 0)
+	if remaining < 0 {
+		remaining = 0
 	}
-	return 0
+	return limiter.Max - uint32(remaining)
 }
 
 // RetriesLeft get user retries left
 func (limiter *rateLimiterDriver) RetriesLeft() uint32 {
-	old := limiter.Cache.Int(limiter.Key, 0)
-	if old < 0 {
-		old = 0
-	}
-	if limiter.Cache.Exists(limiter.Key) {
-		return limiter.Max - limiter.TotalAttempts()
-	}
-	return 0
+	return limiter.Max - limiter.TotalAttempts()
 }
 
 // AvailableIn get time until unlock
 func (limiter *rateLimiterDriver) AvailableIn() time.Duration {
-	if limiter.Cache.Exists(limiter.Key) {
-		return limiter.Cache.TTL(limiter.Key)
+	now := unixMilli(limiter.clock())
+	if end, ok := limiter.window(now); ok {
+		return time.Duration(end-now) * time.Millisecond
 	}
 	return 0
 }
+
+// Window get current window start and end time
+// return zero times if no window started
+func (limiter *rateLimiterDriver) Window() (time.Time, time.Time) {
+	end, ok := limiter.window(unixMilli(limiter.clock()))
+	if !ok {
+		return time.Time{}, time.Time{}
+	}
+	ends := time.Unix(0, end*int64(time.Millisecond))
+	return ends.Add(-limiter.TTL), ends
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock controllable clock
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Now().Truncate(time.Millisecond)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimiterWindowRestart(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := newTestClock()
+			limiter := NewRateLimiterWithOptions("restart", 2, time.Minute, cache, RateLimiterOptions{Clock: clock.Now})
+			defer limiter.Reset()
+
+			if start, end := limiter.Window(); !start.IsZero() || !end.IsZero() {
+				t.Fatal("window started before first hit")
+			}
+			first := clock.Now()
+			if res := limiter.Hit(); !res.Allowed || res.Remaining != 1 || res.ResetIn != time.Minute {
+				t.Fatalf("first hit %+v", res)
+			}
+			clock.Advance(10 * time.Second)
+			if res := limiter.Hit(); !res.Allowed || res.Remaining != 0 || res.ResetIn != 50*time.Second {
+				t.Fatalf("second hit %+v", res)
+			}
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter != 50*time.Second {
+				t.Fatalf("exceeded hit %+v", res)
+			}
+			if !limiter.MustLock() || limiter.TotalAttempts() != 2 || limiter.AvailableIn() != 50*time.Second {
+				t.Fatal("limiter not locked")
+			}
+			if start, end := limiter.Window(); !start.Equal(first) || !end.Equal(first.Add(time.Minute)) {
+				t.Fatalf("window %s - %s", start, end)
+			}
+
+			clock.Advance(50 * time.Second)
+			if limiter.MustLock() || limiter.TotalAttempts() != 0 || limiter.AvailableIn() != 0 {
+				t.Fatal("limiter locked after window expired")
+			}
+			if start, end := limiter.Window(); !start.IsZero() || !end.IsZero() {
+				t.Fatal("window not expired")
+			}
+
+			restart := clock.Now()
+			if res := limiter.Hit(); !res.Allowed || res.Remaining != 1 || res.ResetIn != time.Minute {
+				t.Fatalf("hit after expiration %+v", res)
+			}
+			if start, _ := limiter.Window(); !start.Equal(restart) {
+				t.Fatalf("window not restarted at %s", start)
+			}
+		})
+	}
+}
+
+func TestRateLimiterLock(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := newTestClock()
+			limiter := NewRateLimiterWithOptions("lock", 5, time.Minute, cache, RateLimiterOptions{Clock: clock.Now})
+			defer limiter.Reset()
+
+			limiter.Lock()
+			if !limiter.MustLock() || limiter.RetriesLeft() != 0 || limiter.AvailableIn() != time.Minute {
+				t.Fatal("lock not started locked window")
+			}
+
+			clock.Advance(time.Minute)
+			if limiter.MustLock() || limiter.RetriesLeft() != 5 {
+				t.Fatal("lock not expired")
+			}
+			limiter.Hit()
+			clock.Advance(30 * time.Second)
+			limiter.Lock()
+			if !limiter.MustLock() || limiter.AvailableIn() != 30*time.Second {
+				t.Fatal("lock changed current window")
+			}
+		})
+	}
+}
+
+func TestRateLimiterUnavailable(t *testing.T) {
+	cache := plainCache{NewFileCache("test", t.TempDir())}
+	limiters := map[string]RateLimiter{
+		"window":  NewRateLimiter("window", 5, time.Minute, cache),
+		"log":     NewSlidingWindowLimiter("log", 5, time.Minute, cache),
+		"counter": NewSlidingWindowCounterLimiter("counter", 5, time.Minute, cache),
+		"backoff": NewBackoffLimiter("backoff", 5, time.Minute, []time.Duration{time.Minute}, time.Hour, cache),
+	}
+	for kind, limiter := range limiters {
+		if res := limiter.Hit(); res.Allowed || res.Err != ErrLockNotSupported || res.Limit != 5 {
+			t.Fatalf("%s: hit without lock %+v", kind, res)
+		}
+	}
+	if limiter := NewBackoffLimiter("backoff", 5, time.Minute, []time.Duration{time.Minute}, time.Hour, cache); limiter.MustLock() {
+		t.Fatal("failed hit started lockout")
+	}
+}
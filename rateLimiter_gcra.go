@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// gcraMaxRetries maximum compare and swap retries on concurrent hits
+const gcraMaxRetries = 10
+
+// gcraLimiter generic cell rate algorithm rate limiter
+// only theoretical arrival time (tat) stored as unix nano timestamp
+type gcraLimiter struct {
+	Key      string
+	Interval time.Duration // emission interval, time to regain one token
+	Burst    uint32
+	Cache    Cache
+
+	clock func() time.Time
+}
+
+func (limiter *gcraLimiter) init(key string, rate float64, burst uint32, cache Cache) error {
+	// reject non-positive, nan and out of range rates
+	interval := float64(time.Second) / rate
+	if !(interval >= 1 && interval < math.MaxInt64) {
+		return errors.New("cache: invalid gcra rate")
+	}
+	limiter.Key = key
+	limiter.Interval = time.Duration(interval)
+	limiter.Burst = burst
+	limiter.Cache = cache
+	limiter.clock = time.Now
+	return nil
+}
+
+// tolerance get burst tolerance
+func (limiter *gcraLimiter) tolerance() time.Duration {
+	return limiter.Interval * time.Duration(limiter.Burst)
+}
+
+// tat get stored theoretical arrival time, zero if not exists
+func (limiter *gcraLimiter) tat() int64 {
+	return limiter.Cache.Int64(limiter.Key, 0)
+}
+
+// used get used tokens at time
+func (limiter *gcraLimiter) used(tat int64, now int64) uint32 {
+	if tat <= now {
+		return 0
+	}
+	used := (time.Duration(tat-now) + limiter.Interval - 1) / limiter.Interval
+	if used > time.Duration(limiter.Burst) {
+		return limiter.Burst
+	}
+	return uint32(used)
+}
+
+// retryAfter get time until cost tokens available
+func (limiter *gcraLimiter) retryAfter(tat int64, now int64, cost uint32) time.Duration {
+	if tat < now {
+		tat = now
+	}
+	wait := time.Duration(tat-now) + limiter.Interval*time.Duration(cost) - limiter.tolerance()
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Hit decrease the allowed times and return hit result atomically
+func (limiter *gcraLimiter) Hit() Result {
+	return limiter.HitN(1)
+}
+
+// HitN decrease the allowed times by cost and return hit result atomically
+// cost above burst never fit and rejected without retry after
+// zero cost only report current state and store nothing
+func (limiter *gcraLimiter) HitN(cost uint32) Result {
+	for i := 0; i < gcraMaxRetries; i++ {
+		now := limiter.clock().UnixNano()
+		old := limiter.tat()
+		tat := old
+		if tat < now {
+			tat = now
+		}
+		if cost > limiter.Burst || cost == 0 {
+			return Result{
+				Allowed:   cost == 0,
+				Limit:     limiter.Burst,
+				Remaining: limiter.Burst - limiter.used(old, now),
+				ResetIn:   time.Duration(tat - now),
+			}
+		}
+		if wait := limiter.retryAfter(old, now, cost); wait > 0 {
+			return Result{
+				Limit:      limiter.Burst,
+				Remaining:  limiter.Burst - limiter.used(old, now),
+				ResetIn:    time.Duration(tat - now),
+				RetryAfter: wait,
+			}
+		}
+
+		tat += int64(limiter.Interval * time.Duration(cost))
+		if compareAndSwap(limiter.Cache, limiter.Key, old, tat, time.Duration(tat-now)) {
+			return Result{
+				Allowed:   true,
+				Limit:     limiter.Burst,
+				Remaining: limiter.Burst - limiter.used(tat, now),
+				ResetIn:   time.Duration(tat - now),
+			}
+		}
+	}
+	return unavailable(limiter.Burst, nil)
+}
+
+// Lock lock rate limiter
+func (limiter *gcraLimiter) Lock() {
+	limiter.Cache.Put(limiter.Key, limiter.clock().Add(limiter.tolerance()).UnixNano(), limiter.tolerance())
+}
+
+// Reset reset rate limiter
+func (limiter *gcraLimiter) Reset() {
+	limiter.Cache.Forget(limiter.Key)
+}
+
+// MustLock check if rate limiter must lock access
+func (limiter *gcraLimiter) MustLock() bool {
+	return limiter.AvailableIn() > 0
+}
+
+// TotalAttempts get user attempts count
+func (limiter *gcraLimiter) TotalAttempts() uint32 {
+	return limiter.used(limiter.tat(), limiter.clock().UnixNano())
+}
+
+// RetriesLeft get user retries left
+func (limiter *gcraLimiter) RetriesLeft() uint32 {
+	return limiter.Burst - limiter.TotalAttempts()
+}
+
+// AvailableIn get time until unlock
+func (limiter *gcraLimiter) AvailableIn() time.Duration {
+	return limiter.retryAfter(limiter.tat(), limiter.clock().UnixNano(), 1)
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGCRAInvalidRate(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	for _, rate := range []float64{0, -1, math.NaN(), math.Inf(1), 1e-10, 2e9} {
+		if NewGCRALimiter("limiter", rate, 5, cache) != nil {
+			t.Fatalf("rate %v accepted", rate)
+		}
+	}
+	if NewGCRALimiter("limiter", 0.5, 5, cache) == nil {
+		t.Fatal("valid rate rejected")
+	}
+}
+
+// newTestGCRA create gcra limiter driven by test clock
+func newTestGCRA(t *testing.T, limiter WeightedRateLimiter) (*gcraLimiter, *testClock) {
+	t.Helper()
+	if limiter == nil {
+		t.Fatal("valid rate rejected")
+	}
+	clock := &testClock{now: time.Now()}
+	gcra := limiter.(*gcraLimiter)
+	gcra.clock = clock.Now
+	return gcra, clock
+}
+
+func TestGCRABurstRefill(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter, clock := newTestGCRA(t, NewGCRALimiter("gcra", 1, 5, cache))
+			defer limiter.Reset()
+
+			for i := uint32(1); i <= 5; i++ {
+				if res := limiter.Hit(); !res.Allowed || res.Remaining != 5-i {
+					t.Fatalf("hit %d %+v", i, res)
+				}
+			}
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter != time.Second || res.ResetIn != 5*time.Second {
+				t.Fatalf("hit over burst %+v", res)
+			}
+
+			clock.Advance(2 * time.Second)
+			if limiter.RetriesLeft() != 2 || limiter.AvailableIn() != 0 {
+				t.Fatalf("refill after 2s, %d retries left", limiter.RetriesLeft())
+			}
+			for i := 0; i < 2; i++ {
+				if !limiter.Hit().Allowed {
+					t.Fatalf("refilled hit %d rejected", i+1)
+				}
+			}
+			if limiter.Hit().Allowed {
+				t.Fatal("hit allowed over refilled tokens")
+			}
+		})
+	}
+}
+
+func TestGCRAWeighted(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter, clock := newTestGCRA(t, NewGCRALimiter("gcra-weighted", 1, 5, cache))
+			defer limiter.Reset()
+
+			if res := limiter.HitN(0); !res.Allowed || res.Remaining != 5 {
+				t.Fatalf("zero cost on new key %+v", res)
+			}
+			if res := limiter.HitN(6); res.Allowed || res.RetryAfter != 0 || limiter.RetriesLeft() != 5 {
+				t.Fatalf("cost over burst %+v", res)
+			}
+			if res := limiter.HitN(3); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("cost 3 %+v", res)
+			}
+			if res := limiter.HitN(3); res.Allowed || res.RetryAfter != time.Second || res.Remaining != 2 {
+				t.Fatalf("cost 3 over remaining %+v", res)
+			}
+			if res := limiter.HitN(0); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("zero cost %+v", res)
+			}
+			clock.Advance(time.Second)
+			if res := limiter.HitN(3); !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("cost 3 after retry after %+v", res)
+			}
+		})
+	}
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter, clock := newTestGCRA(t, NewTokenBucketLimiter("bucket", 10, 3, cache))
+			defer limiter.Reset()
+
+			if limiter.Interval != 100*time.Millisecond || limiter.Burst != 3 {
+				t.Fatalf("bucket interval %v burst %d", limiter.Interval, limiter.Burst)
+			}
+			if !limiter.HitN(3).Allowed {
+				t.Fatal("full bucket rejected")
+			}
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter != 100*time.Millisecond {
+				t.Fatalf("empty bucket %+v", res)
+			}
+			clock.Advance(100 * time.Millisecond)
+			if !limiter.Hit().Allowed {
+				t.Fatal("refilled token rejected")
+			}
+			limiter.Lock()
+			if !limiter.MustLock() || limiter.RetriesLeft() != 0 {
+				t.Fatal("lock not locked bucket")
+			}
+			limiter.Reset()
+			if limiter.MustLock() || limiter.RetriesLeft() != 3 {
+				t.Fatal("reset not refilled bucket")
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package cache
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var redisSlidingLogHitScript = redis.NewScript(-1, `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local allowed = 0
+if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	allowed = 1
+end
+local result = {allowed}
+local entries = redis.call("ZRANGE", KEYS[1], 0, -1, "WITHSCORES")
+for i = 2, #entries, 2 do
+	table.insert(result, tonumber(entries[i]))
+end
+return result`)
+
+var redisSlidingLogLockScript = redis.NewScript(-1, `
+redis.call("DEL", KEYS[1])
+for i = 1, tonumber(ARGV[3]) do
+	redis.call("ZADD", KEYS[1], ARGV[1], "lock-" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1`)
+
+var redisSlidingCounterHitScript = redis.NewScript(-1, `
+local window = tonumber(ARGV[4])
+local current = tonumber(redis.call("HGET", KEYS[1], ARGV[1])) or 0
+local previous = tonumber(redis.call("HGET", KEYS[1], ARGV[2])) or 0
+local allowed = 0
+if previous * (window - tonumber(ARGV[3])) / window + current + 1 <= tonumber(ARGV[5]) then
+	current = redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+	redis.call("PEXPIRE", KEYS[1], 2 * window)
+	allowed = 1
+end
+for _, field in ipairs(redis.call("HKEYS", KEYS[1])) do
+	if field ~= ARGV[1] and field ~= ARGV[2] then
+		redis.call("HDEL", KEYS[1], field)
+	end
+end
+return {allowed, current, previous}`)
+
+// slidingLogLimiter sliding window log rate limiter
+// keep timestamp of hits in window, limited to max entries
+type slidingLogLimiter struct {
+	Key    string
+	Max    uint32
+	Window time.Duration
+	Cache  Cache
+	clock  func() time.Time
+}
+
+func (limiter *slidingLogLimiter) init(key string, maxAttempts uint32, window time.Duration, cache Cache) {
+	limiter.Key = key
+	limiter.Max = maxAttempts
+	limiter.Window = window
+	limiter.Cache = cache
+	limiter.clock = time.Now
+}
+
+// trim remove timestamps out of window
+func (limiter *slidingLogLimiter) trim(log []int64, now int64) []int64 {
+	res := make([]int64, 0, len(log))
+	for _, ts := range log {
+		if ts > now-milliseconds(limiter.Window) {
+			res = append(res, ts)
+		}
+	}
+	return res
+}
+
+// timestamps get hit timestamps in window
+func (limiter *slidingLogLimiter) timestamps(now int64) []int64 {
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		conn := rc.client()
+		defer conn.Close()
+		entries, err := redis.Strings(conn.Do("ZRANGEBYSCORE", rc.prefixer(limiter.Key), "("+strconv.FormatInt(now-milliseconds(limiter.Window), 10), "+inf", "WITHSCORES"))
+		if err != nil {
+			return nil
+		}
+		log := make([]int64, 0, len(entries)/2)
+		for i := 1; i < len(entries); i += 2 {
+			if ts, err := strconv.ParseFloat(entries[i], 64); err == nil {
+				log = append(log, int64(ts))
+			}
+		}
+		return log
+	}
+
+	log, _ := limiter.Cache.Get(limiter.Key).([]int64)
+	return limiter.trim(log, now)
+}
+
+// result create hit result from timestamps in window
+func (limiter *slidingLogLimiter) result(allowed bool, log []int64, now int64) Result {
+	res := Result{Allowed: allowed, Limit: limiter.Max}
+	if count := uint32(len(log)); count < limiter.Max {
+		res.Remaining = limiter.Max - count
+	}
+	if len(log) > 0 {
+		res.ResetIn = time.Duration(log[len(log)-1]+milliseconds(limiter.Window)-now) * time.Millisecond
+	}
+	if !allowed {
+		res.RetryAfter = limiter.retryAfter(log, now)
+	}
+	return res
+}
+
+// retryAfter get time until oldest hit leave window
+func (limiter *slidingLogLimiter) retryAfter(log []int64, now int64) time.Duration {
+	if uint32(len(log)) < limiter.Max || limiter.Max == 0 {
+		return 0
+	}
+	oldest := log[uint32(len(log))-limiter.Max]
+	return time.Duration(oldest+milliseconds(limiter.Window)-now) * time.Millisecond
+}
+
+// Hit decrease the allowed times and return hit result atomically
+func (limiter *slidingLogLimiter) Hit() Result {
+	now := unixMilli(limiter.clock())
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		member, err := randomToken()
+		if err != nil {
+			return unavailable(limiter.Max, err)
+		}
+		reply, err := redis.Int64s(rc.eval(redisSlidingLogHitScript, []string{limiter.Key}, now, milliseconds(limiter.Window), limiter.Max, member))
+		if err != nil || len(reply) == 0 {
+			return unavailable(limiter.Max, err)
+		}
+		return limiter.result(reply[0] == 1, reply[1:], now)
+	}
+
+	res := unavailable(limiter.Max, nil)
+	err := synchronize(limiter.Cache, limiter.Key, func() {
+		log := limiter.timestamps(now)
+		allowed := uint32(len(log)) < limiter.Max
+		if allowed {
+			log = append(log, now)
+			if !limiter.Cache.Put(limiter.Key, log, limiter.Window) {
+				return
+			}
+		}
+		res = limiter.result(allowed, log, now)
+	})
+	if err != nil {
+		return unavailable(limiter.Max, err)
+	}
+	return res
+}
+
+// Lock lock rate limiter
+func (limiter *slidingLogLimiter) Lock() {
+	now := unixMilli(limiter.clock())
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		rc.eval(redisSlidingLogLockScript, []string{limiter.Key}, now, milliseconds(limiter.Window), limiter.Max)
+		return
+	}
+
+	log := make([]int64, limiter.Max)
+	for i := range log {
+		log[i] = now
+	}
+	synchronize(limiter.Cache, limiter.Key, func() {
+		limiter.Cache.Put(limiter.Key, log, limiter.Window)
+	})
+}
+
+// Reset reset rate limiter
+func (limiter *slidingLogLimiter) Reset() {
+	limiter.Cache.Forget(limiter.Key)
+}
+
+// MustLock check if rate limiter must lock access
+func (limiter *slidingLogLimiter) MustLock() bool {
+	return limiter.TotalAttempts() >= limiter.Max
+}
+
+// TotalAttempts get user attempts count
+func (limiter *slidingLogLimiter) TotalAttempts() uint32 {
+	count := uint32(len(limiter.timestamps(unixMilli(limiter.clock()))))
+	if count > limiter.Max {
+		return limiter.Max
+	}
+	return count
+}
+
+// RetriesLeft get user retries left
+func (limiter *slidingLogLimiter) RetriesLeft() uint32 {
+	return limiter.Max - limiter.TotalAttempts()
+}
+
+// AvailableIn get time until unlock
+func (limiter *slidingLogLimiter) AvailableIn() time.Duration {
+	now := unixMilli(limiter.clock())
+	return limiter.retryAfter(limiter.timestamps(now), now)
+}
+
+// slidingCounterLimiter sliding window counter rate limiter
+// estimate hits in window from current and previous fixed window counters
+type slidingCounterLimiter struct {
+	Key    string
+	Max    uint32
+	Window time.Duration
+	Cache  Cache
+	clock  func() time.Time
+}
+
+func (limiter *slidingCounterLimiter) init(key string, maxAttempts uint32, window time.Duration, cache Cache) {
+	limiter.Key = key
+	limiter.Max = maxAttempts
+	limiter.Window = window
+	limiter.Cache = cache
+	limiter.clock = time.Now
+}
+
+// position get current fixed window index and elapsed time of window in milliseconds
+func (limiter *slidingCounterLimiter) position(now int64) (int64, int64) {
+	window := milliseconds(limiter.Window)
+	return now / window, now % window
+}
+
+// counters get current and previous window counters
+func (limiter *slidingCounterLimiter) counters(index int64) (int64, int64) {
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		conn := rc.client()
+		defer conn.Close()
+		values, err := redis.Values(conn.Do("HMGET", rc.prefixer(limiter.Key), index, index-1))
+		if err != nil || len(values) != 2 {
+			return 0, 0
+		}
+		current, _ := redis.Int64(values[0], nil)
+		previous, _ := redis.Int64(values[1], nil)
+		return current, previous
+	}
+
+	// state stored as window index, current counter and previous counter
+	state, _ := limiter.Cache.Get(limiter.Key).([]int64)
+	if len(state) != 3 {
+		return 0, 0
+	}
+	switch state[0] {
+	case index:
+		return state[1], state[2]
+	case index - 1:
+		return 0, state[1]
+	default:
+		return 0, 0
+	}
+}
+
+// weighted get estimated hits count in sliding window
+func (limiter *slidingCounterLimiter) weighted(current int64, previous int64, elapsed int64) float64 {
+	window := float64(milliseconds(limiter.Window))
+	return float64(previous)*(window-float64(elapsed))/window + float64(current)
+}
+
+// retryAfter get time until estimated hits count drop below max
+func (limiter *slidingCounterLimiter) retryAfter(current int64, previous int64, elapsed int64) time.Duration {
+	max := int64(limiter.Max)
+	window := milliseconds(limiter.Window)
+	if limiter.weighted(current, previous, elapsed)+1 <= float64(max) {
+		return 0
+	}
+	var wait float64
+	if current+1 > max || previous == 0 {
+		// current window counter become previous counter of next window
+		wait = float64(window - elapsed)
+		if current > 0 {
+			wait += math.Max(0, float64(window)-float64((max-1)*window)/float64(current))
+		}
+	} else {
+		wait = math.Max(0, float64(window-elapsed)-float64((max-1-current)*window)/float64(previous))
+	}
+	return time.Duration(math.Ceil(wait)) * time.Millisecond
+}
+
+// result create hit result from window counters
+func (limiter *slidingCounterLimiter) result(allowed bool, current int64, previous int64, elapsed int64) Result {
+	window := milliseconds(limiter.Window)
+	res := Result{Allowed: allowed, Limit: limiter.Max}
+	if remaining := math.Floor(float64(limiter.Max) - limiter.weighted(current, previous, elapsed)); remaining > 0 {
+		res.Remaining = uint32(remaining)
+	}
+	if current > 0 {
+		res.ResetIn = time.Duration(2*window-elapsed) * time.Millisecond
+	} else if previous > 0 {
+		res.ResetIn = time.Duration(window-elapsed) * time.Millisecond
+	}
+	if !allowed {
+		res.RetryAfter = limiter.retryAfter(current, previous, elapsed)
+	}
+	return res
+}
+
+// Hit decrease the allowed times and return hit result atomically
+func (limiter *slidingCounterLimiter) Hit() Result {
+	index, elapsed := limiter.position(unixMilli(limiter.clock()))
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		reply, err := redis.Int64s(rc.eval(redisSlidingCounterHitScript, []string{limiter.Key}, index, index-1, elapsed, milliseconds(limiter.Window), limiter.Max))
+		if err != nil || len(reply) != 3 {
+			return unavailable(limiter.Max, err)
+		}
+		return limiter.result(reply[0] == 1, reply[1], reply[2], elapsed)
+	}
+
+	res := unavailable(limiter.Max, nil)
+	err := synchronize(limiter.Cache, limiter.Key, func() {
+		current, previous := limiter.counters(index)
+		allowed := limiter.weighted(current, previous, elapsed)+1 <= float64(limiter.Max)
+		if allowed {
+			current++
+			if !limiter.Cache.Put(limiter.Key, []int64{index, current, previous}, 2*limiter.Window) {
+				return
+			}
+		}
+		res = limiter.result(allowed, current, previous, elapsed)
+	})
+	if err != nil {
+		return unavailable(limiter.Max, err)
+	}
+	return res
+}
+
+// Lock lock rate limiter
+func (limiter *slidingCounterLimiter) Lock() {
+	index, _ := limiter.position(unixMilli(limiter.clock()))
+	if rc, ok := limiter.Cache.(*redisCache); ok {
+		conn := rc.client()
+		defer conn.Close()
+		conn.Send("MULTI")
+		conn.Send("HSET", rc.prefixer(limiter.Key), index, limiter.Max)
+		conn.Send("PEXPIRE", rc.prefixer(limiter.Key), milliseconds(2*limiter.Window))
+		conn.Do("EXEC")
+		return
+	}
+
+	synchronize(limiter.Cache, limiter.Key, func() {
+		_, previous := limiter.counters(index)
+		limiter.Cache.Put(limiter.Key, []int64{index, int64(limiter.Max), previous}, 2*limiter.Window)
+	})
+}
+
+// Reset reset rate limiter
+func (limiter *slidingCounterLimiter) Reset() {
+	limiter.Cache.Forget(limiter.Key)
+}
+
+// MustLock check if rate limiter must lock access
+func (limiter *slidingCounterLimiter) MustLock() bool {
+	return limiter.AvailableIn() > 0
+}
+
+// TotalAttempts get user attempts count
+func (limiter *slidingCounterLimiter) TotalAttempts() uint32 {
+	index, elapsed := limiter.position(unixMilli(limiter.clock()))
+	current, previous := limiter.counters(index)
+	total := math.Ceil(limiter.weighted(current, previous, elapsed))
+	if total > float64(limiter.Max) {
+		return limiter.Max
+	}
+	return uint32(total)
+}
+
+// RetriesLeft get user retries left
+func (limiter *slidingCounterLimiter) RetriesLeft() uint32 {
+	return limiter.Max - limiter.TotalAttempts()
+}
+
+// AvailableIn get time until unlock
+func (limiter *slidingCounterLimiter) AvailableIn() time.Duration {
+	index, elapsed := limiter.position(unixMilli(limiter.clock()))
+	current, previous := limiter.counters(index)
+	return limiter.retryAfter(current, previous, elapsed)
+}
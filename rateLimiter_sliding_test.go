@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// boundaryClock get clock one second before end of fixed minute window
+func boundaryClock() *testClock {
+	now := time.Now().Truncate(time.Minute).Add(59 * time.Second)
+	return &testClock{now: now}
+}
+
+func TestSlidingLogBoundaryBurst(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := boundaryClock()
+			limiter := NewSlidingWindowLimiter("log", 5, time.Minute, cache).(*slidingLogLimiter)
+			limiter.clock = clock.Now
+			defer limiter.Reset()
+
+			for i := 0; i < 5; i++ {
+				if !limiter.Hit().Allowed {
+					t.Fatalf("hit %d rejected", i+1)
+				}
+			}
+			clock.Advance(2 * time.Second)
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter != 58*time.Second {
+				t.Fatalf("burst after fixed window boundary %+v", res)
+			}
+			clock.Advance(58 * time.Second)
+			if res := limiter.Hit(); !res.Allowed || res.Remaining != 4 {
+				t.Fatalf("hit after oldest hit left window %+v", res)
+			}
+		})
+	}
+}
+
+func TestSlidingCounterBoundaryBurst(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := boundaryClock()
+			limiter := NewSlidingWindowCounterLimiter("counter", 5, time.Minute, cache).(*slidingCounterLimiter)
+			limiter.clock = clock.Now
+			defer limiter.Reset()
+
+			for i := 0; i < 5; i++ {
+				if !limiter.Hit().Allowed {
+					t.Fatalf("hit %d rejected", i+1)
+				}
+			}
+			clock.Advance(2 * time.Second)
+			res := limiter.Hit()
+			if res.Allowed || res.RetryAfter <= 0 {
+				t.Fatalf("burst after fixed window boundary %+v", res)
+			}
+			clock.Advance(res.RetryAfter - time.Millisecond)
+			if limiter.Hit().Allowed {
+				t.Fatal("hit allowed before retry after")
+			}
+			clock.Advance(time.Millisecond)
+			if !limiter.Hit().Allowed {
+				t.Fatal("hit rejected after retry after")
+			}
+		})
+	}
+}
+
+func TestSlidingCounterRetryAfter(t *testing.T) {
+	limiter := NewSlidingWindowCounterLimiter("counter", 5, time.Second, NewFileCache("test", t.TempDir())).(*slidingCounterLimiter)
+	window := milliseconds(limiter.Window)
+
+	// allowed check after advance ms from counters state, counters shifted on window change
+	allowed := func(current int64, previous int64, elapsed int64, advance int64) bool {
+		elapsed += advance
+		for elapsed >= window {
+			previous, current = current, 0
+			elapsed -= window
+		}
+		return limiter.weighted(current, previous, elapsed)+1 <= float64(limiter.Max)
+	}
+
+	for current := int64(0); current <= 6; current++ {
+		for previous := int64(0); previous <= 6; previous++ {
+			for elapsed := int64(0); elapsed < window; elapsed += 125 {
+				wait := milliseconds(limiter.retryAfter(current, previous, elapsed))
+				if !allowed(current, previous, elapsed, wait) {
+					t.Fatalf("current %d previous %d elapsed %d: rejected after wait %dms", current, previous, elapsed, wait)
+				}
+				if wait > 0 && allowed(current, previous, elapsed, wait-1) {
+					t.Fatalf("current %d previous %d elapsed %d: allowed before wait %dms", current, previous, elapsed, wait)
+				}
+			}
+		}
+	}
+}
+
+func TestSlidingLockReset(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			limiters := map[string]RateLimiter{
+				"log":     NewSlidingWindowLimiter("log-lock", 5, time.Minute, cache),
+				"counter": NewSlidingWindowCounterLimiter("counter-lock", 5, time.Minute, cache),
+			}
+			for kind, limiter := range limiters {
+				limiter.Lock()
+				if !limiter.MustLock() || limiter.RetriesLeft() != 0 || limiter.AvailableIn() <= 0 {
+					t.Fatalf("%s: lock not locked limiter", kind)
+				}
+				if limiter.Hit().Allowed {
+					t.Fatalf("%s: hit allowed on locked limiter", kind)
+				}
+				limiter.Reset()
+				if limiter.MustLock() || limiter.RetriesLeft() != 5 || !limiter.Hit().Allowed {
+					t.Fatalf("%s: reset not unlocked limiter", kind)
+				}
+				limiter.Reset()
+			}
+		})
+	}
+}
@@ -1,11 +1,42 @@
 package cache
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrLimiterUnavailable error returned if rate limiter state could not be read or stored
+var ErrLimiterUnavailable = errors.New("cache: rate limiter state not available")
+
+// Result rate limiter hit result
+type Result struct {
+	// Allowed is true if hit accepted
+	Allowed bool
+	// Limit maximum allowed hits
+	Limit uint32
+	// Remaining retries left after hit
+	Remaining uint32
+	// ResetIn time until limiter reset
+	ResetIn time.Duration
+	// RetryAfter time until next hit allowed, zero if hit accepted
+	RetryAfter time.Duration
+	// Err is set if limiter state could not be read or stored
+	// hit neither accepted nor counted and caller decide to fail open or closed
+	Err error
+}
+
+// RateLimiterOptions fixed window and backoff rate limiter options
+type RateLimiterOptions struct {
+	// Clock time source deciding window expiration, time.Now used if nil
+	Clock func() time.Time
+	// Observer receive exceeded, locked and reset events, no events sent if nil
+	Observer Observer
+}
 
 // RateLimiter interface for rate limiter
 type RateLimiter interface {
-	// Hit decrease the allowed times
-	Hit()
+	// Hit decrease the allowed times and return hit result atomically
+	Hit() Result
 	// Lock lock rate limiter
 	Lock()
 	// Reset reset rate limiter
@@ -19,3 +50,49 @@ type RateLimiter interface {
 	// AvailableIn get time until unlock
 	AvailableIn() time.Duration
 }
+
+// WindowRateLimiter interface for fixed window rate limiter
+type WindowRateLimiter interface {
+	RateLimiter
+	// Window get current window start and end time
+	Window() (time.Time, time.Time)
+}
+
+// WeightedRateLimiter interface for rate limiter with weighted hits
+type WeightedRateLimiter interface {
+	RateLimiter
+	// HitN decrease the allowed times by cost and return hit result atomically
+	HitN(cost uint32) Result
+}
+
+// Limit rate limit tier of composite rate limiter
+type Limit struct {
+	// Name of limit, reported when limit exhausted
+	Name string
+	// Max allowed hits in window
+	Max uint32
+	// Window duration
+	Window time.Duration
+}
+
+// CompositeResult composite rate limiter hit result
+type CompositeResult struct {
+	Result
+	// Triggered name of exhausted limit, empty if hit accepted
+	Triggered string
+}
+
+// CompositeLimiter interface for rate limiter combining multiple limits
+type CompositeLimiter interface {
+	RateLimiter
+	// HitAll hit all limits atomically and return hit result with exhausted limit name
+	// no hit recorded if any limit exhausted
+	HitAll() CompositeResult
+}
+
+// BackoffLimiter interface for rate limiter with progressive lockout
+type BackoffLimiter interface {
+	RateLimiter
+	// Strikes get lockout escalation level
+	Strikes() uint32
+}
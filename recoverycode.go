@@ -0,0 +1,18 @@
+package cache
+
+import "errors"
+
+// ErrRecoveryCodesNotStored error returned when generated recovery codes could not be stored in cache
+var ErrRecoveryCodesNotStored = errors.New("cache: recovery codes not stored")
+
+// RecoveryCodes interface for single use account recovery (backup) codes
+type RecoveryCodes interface {
+	// Generate generate new set of count codes, previous codes invalidated
+	Generate(count uint) ([]string, error)
+	// Verify check code and consume it if matched
+	Verify(code string) (bool, error)
+	// Remaining get unused codes count
+	Remaining() uint32
+	// Clear remove all codes
+	Clear() bool
+}
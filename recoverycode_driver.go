@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// recoveryDriver recovery codes manager
+// codes stored as salted keyed hash list under single key without expiration
+type recoveryDriver struct {
+	Key   string
+	Cache Cache
+	codes vcDriver
+}
+
+func (recovery *recoveryDriver) init(key string, secret []byte, cache Cache) error {
+	recovery.Key = key
+	recovery.Cache = cache
+	return recovery.codes.init(key, 0, cache, VerificationOptions{
+		Secret:          secret,
+		Charset:         UnambiguousCharset,
+		Format:          "#####-#####",
+		CaseInsensitive: true,
+	})
+}
+
+// hashes get stored code hashes
+func (recovery *recoveryDriver) hashes() []string {
+	var hashes []string
+	encoded := recovery.Cache.String(recovery.Key, "")
+	if encoded == "" || json.Unmarshal([]byte(encoded), &hashes) != nil {
+		return nil
+	}
+	return hashes
+}
+
+// store store code hashes forever
+func (recovery *recoveryDriver) store(hashes []string) bool {
+	encoded, err := json.Marshal(hashes)
+	if err != nil {
+		return false
+	}
+	return recovery.Cache.PutForever(recovery.Key, string(encoded))
+}
+
+// Generate generate new set of count codes, previous codes invalidated
+func (recovery *recoveryDriver) Generate(count uint) ([]string, error) {
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+	for i := uint(0); i < count; i++ {
+		code, err := recovery.codes.format()
+		if err != nil {
+			return nil, err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hex.EncodeToString(salt)+"$"+hex.EncodeToString(recovery.codes.hash(code, salt, "")))
+	}
+
+	stored := false
+	if err := synchronize(recovery.Cache, recovery.Key, func() {
+		stored = recovery.store(hashes)
+	}); err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, ErrRecoveryCodesNotStored
+	}
+	return codes, nil
+}
+
+// Verify check code and consume it if matched
+// all codes checked to not leak matched code position
+func (recovery *recoveryDriver) Verify(code string) (bool, error) {
+	valid := false
+	err := synchronize(recovery.Cache, recovery.Key, func() {
+		hashes := recovery.hashes()
+		matched := -1
+		for i, hash := range hashes {
+			if recovery.codes.match(code, hash, "") && matched < 0 {
+				matched = i
+			}
+		}
+		if matched < 0 {
+			return
+		}
+		hashes = append(hashes[:matched], hashes[matched+1:]...)
+		valid = recovery.store(hashes)
+	})
+	if err != nil {
+		return false, err
+	}
+	return valid, nil
+}
+
+// Remaining get unused codes count
+func (recovery *recoveryDriver) Remaining() uint32 {
+	return uint32(len(recovery.hashes()))
+}
+
+// Clear remove all codes
+func (recovery *recoveryDriver) Clear() bool {
+	return recovery.Cache.Forget(recovery.Key)
+}
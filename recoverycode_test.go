@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCodesVerify(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			recovery, err := NewRecoveryCodes("recovery", []byte("secret"), cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer recovery.Clear()
+			codes, err := recovery.Generate(5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(codes) != 5 || recovery.Remaining() != 5 {
+				t.Fatalf("%d codes generated, %d remaining", len(codes), recovery.Remaining())
+			}
+
+			for i, code := range codes {
+				input := code
+				if i%2 == 1 {
+					input = strings.ToLower(strings.Replace(code, "-", "", 1))
+				}
+				if ok, err := recovery.Verify(input); err != nil || !ok {
+					t.Fatalf("code %q rejected", input)
+				}
+				if remaining := recovery.Remaining(); remaining != uint32(4-i) {
+					t.Fatalf("%d codes remaining after %d verified", remaining, i+1)
+				}
+				if ok, _ := recovery.Verify(code); ok {
+					t.Fatalf("code %q reused", code)
+				}
+			}
+			if ok, _ := recovery.Verify("wrong"); ok || recovery.Remaining() != 0 {
+				t.Fatal("unknown code accepted")
+			}
+		})
+	}
+}
+
+func TestRecoveryCodesRegenerate(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			recovery, err := NewRecoveryCodes("regenerate", []byte("secret"), cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer recovery.Clear()
+			old, err := recovery.Generate(3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			codes, err := recovery.Generate(3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, code := range old {
+				if ok, _ := recovery.Verify(code); ok {
+					t.Fatalf("code %q of previous set accepted", code)
+				}
+			}
+			if recovery.Remaining() != 3 {
+				t.Fatal("failed verify consumed code")
+			}
+			if ok, _ := recovery.Verify(codes[1]); !ok || recovery.Remaining() != 2 {
+				t.Fatal("code of new set rejected")
+			}
+			if !recovery.Clear() || recovery.Remaining() != 0 {
+				t.Fatal("codes not cleared")
+			}
+			if ok, _ := recovery.Verify(codes[0]); ok {
+				t.Fatal("cleared code accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package cache
+
+import "time"
+
+// Policy rate limiter policy
+type Policy struct {
+	MaxAttempts uint32
+	TTL         time.Duration
+}
+
+// Override per subject rate limiter policy override
+type Override struct {
+	// Unlimited subject never limited (e.g. whitelisted partner)
+	Unlimited bool `json:"unlimited,omitempty"`
+	// Banned subject always locked
+	Banned bool `json:"banned,omitempty"`
+	// MaxAttempts replace policy max attempts if not zero
+	MaxAttempts uint32 `json:"max_attempts,omitempty"`
+	// TTL replace policy ttl if not zero
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+// Registry interface for named rate limiter policies
+type Registry interface {
+	// Define declare named policy
+	Define(name string, policy Policy)
+	// Limiter resolve rate limiter of policy for subject
+	// return nil if policy not defined
+	Limiter(policy string, subject string) RateLimiter
+	// Override store subject override in cache, zero ttl keep override forever
+	Override(policy string, subject string, override Override, ttl time.Duration) bool
+	// GetOverride get subject override
+	GetOverride(policy string, subject string) (Override, bool)
+	// RemoveOverride remove subject override
+	RemoveOverride(policy string, subject string) bool
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// staticLimiter rate limiter that always allow or always lock
+type staticLimiter struct {
+	Allowed bool
+	Until   time.Duration
+}
+
+// Hit return static hit result
+func (limiter *staticLimiter) Hit() Result {
+	if limiter.Allowed {
+		return Result{Allowed: true, Limit: math.MaxUint32, Remaining: math.MaxUint32}
+	}
+	return Result{ResetIn: limiter.Until, RetryAfter: limiter.Until}
+}
+
+// Lock do nothing
+func (limiter *staticLimiter) Lock() {}
+
+// Reset do nothing
+func (limiter *staticLimiter) Reset() {}
+
+// MustLock check if rate limiter must lock access
+func (limiter *staticLimiter) MustLock() bool {
+	return !limiter.Allowed
+}
+
+// TotalAttempts get user attempts count
+func (limiter *staticLimiter) TotalAttempts() uint32 {
+	return 0
+}
+
+// RetriesLeft get user retries left
+func (limiter *staticLimiter) RetriesLeft() uint32 {
+	if limiter.Allowed {
+		return math.MaxUint32
+	}
+	return 0
+}
+
+// AvailableIn get time until unlock
+func (limiter *staticLimiter) AvailableIn() time.Duration {
+	return limiter.Until
+}
+
+// registryDriver rate limiter registry driver
+type registryDriver struct {
+	Prefix   string
+	Cache    Cache
+	mutex    sync.RWMutex
+	policies map[string]Policy
+}
+
+func (r *registryDriver) init(prefix string, cache Cache) {
+	r.Prefix = prefix
+	r.Cache = cache
+	r.policies = make(map[string]Policy)
+}
+
+// namespaced get key of policy subject in namespace
+// subject hashed so no subject collide with other subject or limiter internal key suffix
+func (r *registryDriver) namespaced(namespace string, policy string, subject string) string {
+	hash := sha256.Sum256([]byte(subject))
+	key := namespace + ":" + policy + ":" + hex.EncodeToString(hash[:])
+	if r.Prefix == "" {
+		return key
+	}
+	return r.Prefix + ":" + key
+}
+
+// key get rate limiter key of policy subject
+func (r *registryDriver) key(policy string, subject string) string {
+	return r.namespaced("limit", policy, subject)
+}
+
+// overrideKey get override key of policy subject
+func (r *registryDriver) overrideKey(policy string, subject string) string {
+	return r.namespaced("override", policy, subject)
+}
+
+// Define declare named policy
+func (r *registryDriver) Define(name string, policy Policy) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.policies[name] = policy
+}
+
+// Limiter resolve rate limiter of policy for subject
+func (r *registryDriver) Limiter(policy string, subject string) RateLimiter {
+	r.mutex.RLock()
+	p, ok := r.policies[policy]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if override, ok := r.GetOverride(policy, subject); ok {
+		if override.Banned {
+			// permanent ban has no ttl, policy ttl used as retry hint
+			until := r.Cache.TTL(r.overrideKey(policy, subject))
+			if until <= 0 {
+				until = p.TTL
+			}
+			return &staticLimiter{Until: until}
+		}
+		if override.Unlimited {
+			return &staticLimiter{Allowed: true}
+		}
+		if override.MaxAttempts > 0 {
+			p.MaxAttempts = override.MaxAttempts
+		}
+		if override.TTL > 0 {
+			p.TTL = override.TTL
+		}
+	}
+	return NewRateLimiter(r.key(policy, subject), p.MaxAttempts, p.TTL, r.Cache)
+}
+
+// Override store subject override in cache, zero ttl keep override forever
+func (r *registryDriver) Override(policy string, subject string, override Override, ttl time.Duration) bool {
+	encoded, err := json.Marshal(override)
+	if err != nil {
+		return false
+	}
+	if ttl == 0 {
+		return r.Cache.PutForever(r.overrideKey(policy, subject), string(encoded))
+	}
+	return r.Cache.Put(r.overrideKey(policy, subject), string(encoded), ttl)
+}
+
+// GetOverride get subject override
+func (r *registryDriver) GetOverride(policy string, subject string) (Override, bool) {
+	var override Override
+	encoded := r.Cache.String(r.overrideKey(policy, subject), "")
+	if encoded == "" || json.Unmarshal([]byte(encoded), &override) != nil {
+		return override, false
+	}
+	return override, true
+}
+
+// RemoveOverride remove subject override
+func (r *registryDriver) RemoveOverride(policy string, subject string) bool {
+	return r.Cache.Forget(r.overrideKey(policy, subject))
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRegistryKeyCollision(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			registry := NewRegistry("app", cache)
+			registry.Define("login", Policy{MaxAttempts: 1, TTL: time.Minute})
+			defer func() {
+				for _, subject := range []string{"bob", "bob-override", "bob-window"} {
+					registry.Limiter("login", subject).Reset()
+					registry.RemoveOverride("login", subject)
+				}
+			}()
+
+			if !registry.Override("login", "bob", Override{Banned: true}, time.Minute) {
+				t.Fatal("override not stored")
+			}
+			if !registry.Limiter("login", "bob-override").Hit().Allowed {
+				t.Fatal("override of other subject applied")
+			}
+			if registry.Override("login", "bob-override", Override{Unlimited: true}, 0); registry.Limiter("login", "bob").Hit().Allowed {
+				t.Fatal("ban replaced by override of other subject")
+			}
+
+			registry.RemoveOverride("login", "bob")
+			if !registry.Limiter("login", "bob").Hit().Allowed || registry.Limiter("login", "bob").Hit().Allowed {
+				t.Fatal("policy not applied after override removed")
+			}
+			if !registry.Limiter("login", "bob-window").Hit().Allowed {
+				t.Fatal("limiter state shared with other subject")
+			}
+			if registry.Limiter("signup", "bob") != nil {
+				t.Fatal("limiter of undefined policy resolved")
+			}
+		})
+	}
+}
+
+func TestRegistryOverride(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			registry := NewRegistry("", cache)
+			registry.Define("api", Policy{MaxAttempts: 5, TTL: time.Minute})
+			defer func() {
+				for _, subject := range []string{"partner", "trial"} {
+					registry.Limiter("api", subject).Reset()
+					registry.RemoveOverride("api", subject)
+				}
+			}()
+
+			registry.Override("api", "trial", Override{MaxAttempts: 1, TTL: time.Hour}, time.Hour)
+			if override, ok := registry.GetOverride("api", "trial"); !ok || override.MaxAttempts != 1 || override.TTL != time.Hour {
+				t.Fatalf("override %+v not stored", override)
+			}
+			limiter := registry.Limiter("api", "trial")
+			if res := limiter.Hit(); !res.Allowed || res.Limit != 1 || res.ResetIn != time.Hour {
+				t.Fatalf("override hit %+v", res)
+			}
+			if limiter.Hit().Allowed {
+				t.Fatal("override max attempts not applied")
+			}
+
+			registry.Override("api", "partner", Override{Unlimited: true}, 0)
+			limiter = registry.Limiter("api", "partner")
+			for i := 0; i < 10; i++ {
+				if !limiter.Hit().Allowed {
+					t.Fatal("unlimited subject limited")
+				}
+			}
+			if limiter.MustLock() || limiter.RetriesLeft() != math.MaxUint32 {
+				t.Fatal("unlimited subject locked")
+			}
+		})
+	}
+}
+
+func TestRegistryBan(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			registry := NewRegistry("app", cache)
+			registry.Define("login", Policy{MaxAttempts: 5, TTL: time.Minute})
+			defer registry.RemoveOverride("login", "mallory")
+			defer registry.RemoveOverride("login", "eve")
+
+			registry.Override("login", "mallory", Override{Banned: true}, time.Hour)
+			limiter := registry.Limiter("login", "mallory")
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter <= time.Minute || res.RetryAfter > time.Hour {
+				t.Fatalf("temporary ban hit %+v", res)
+			}
+			if !limiter.MustLock() || limiter.RetriesLeft() != 0 {
+				t.Fatal("banned subject not locked")
+			}
+
+			registry.Override("login", "eve", Override{Banned: true}, 0)
+			limiter = registry.Limiter("login", "eve")
+			if res := limiter.Hit(); res.Allowed || res.RetryAfter != time.Minute || res.ResetIn != time.Minute {
+				t.Fatalf("permanent ban hit %+v", res)
+			}
+			if limiter.AvailableIn() != time.Minute {
+				t.Fatal("permanent ban without retry hint")
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package cache
+
+import "time"
+
+// Semaphore interface for distributed semaphore
+// each semaphore instance hold at most one lease
+type Semaphore interface {
+	// Acquire try to acquire lease until timeout passed
+	Acquire(timeout time.Duration) bool
+	// TryAcquire try to acquire lease once
+	TryAcquire() bool
+	// Release release lease if held by this semaphore instance
+	Release() bool
+	// Renew reset lease ttl if held by this semaphore instance
+	Renew() bool
+	// Count get number of active leases
+	Count() uint32
+	// Owner get lease owner token
+	Owner() string
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisSemaphoreNow lua prelude reading current unix milliseconds from redis server clock
+// so leases of all clients expire by same clock
+const redisSemaphoreNow = `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+var redisSemaphoreAcquireScript = redis.NewScript(-1, redisSemaphoreNow+`
+local lease = tonumber(ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if not redis.call("ZSCORE", KEYS[1], ARGV[3]) and redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + lease, ARGV[3])
+redis.call("PEXPIRE", KEYS[1], lease)
+return 1`)
+
+var redisSemaphoreRenewScript = redis.NewScript(-1, redisSemaphoreNow+`
+local lease = tonumber(ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if not redis.call("ZSCORE", KEYS[1], ARGV[2]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], "XX", now + lease, ARGV[2])
+redis.call("PEXPIRE", KEYS[1], lease)
+return 1`)
+
+var redisSemaphoreCountScript = redis.NewScript(-1, redisSemaphoreNow+`
+return redis.call("ZCOUNT", KEYS[1], "(" .. now, "+inf")`)
+
+// semaphoreDriver distributed semaphore driver
+// use sorted set of lease expirations on redis and lease lock per slot on other drivers
+type semaphoreDriver struct {
+	Key   string
+	Limit uint32
+	TTL   time.Duration
+	Token string
+	Cache Cache
+	store locker
+	slot  int
+}
+
+func (sem *semaphoreDriver) init(key string, limit uint32, leaseTTL time.Duration, cache Cache, store locker) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	sem.Key = key
+	sem.Limit = limit
+	sem.TTL = leaseTTL
+	sem.Token = token
+	sem.Cache = cache
+	sem.store = store
+	sem.slot = -1
+	return nil
+}
+
+// slotKey get lease slot key
+func (sem *semaphoreDriver) slotKey(slot int) string {
+	return sem.Key + "-slot-" + strconv.Itoa(slot)
+}
+
+// Acquire try to acquire lease until timeout passed
+func (sem *semaphoreDriver) Acquire(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if sem.TryAcquire() {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// TryAcquire try to acquire lease once
+func (sem *semaphoreDriver) TryAcquire() bool {
+	if rc, ok := sem.Cache.(*redisCache); ok {
+		reply, err := redis.Int(rc.eval(redisSemaphoreAcquireScript, []string{sem.Key}, milliseconds(sem.TTL), sem.Limit, sem.Token))
+		return err == nil && reply == 1
+	}
+
+	if sem.slot >= 0 {
+		return sem.Renew()
+	}
+	for slot := 0; slot < int(sem.Limit); slot++ {
+		if sem.store.acquireLock(sem.slotKey(slot), sem.Token, sem.TTL) {
+			sem.slot = slot
+			return true
+		}
+	}
+	return false
+}
+
+// Release release lease if held by this semaphore instance
+func (sem *semaphoreDriver) Release() bool {
+	if rc, ok := sem.Cache.(*redisCache); ok {
+		conn := rc.client()
+		defer conn.Close()
+		reply, err := redis.Int(conn.Do("ZREM", rc.prefixer(sem.Key), sem.Token))
+		return err == nil && reply == 1
+	}
+
+	if sem.slot < 0 {
+		return false
+	}
+	slot := sem.slot
+	sem.slot = -1
+	return sem.store.releaseLock(sem.slotKey(slot), sem.Token)
+}
+
+// Renew reset lease ttl if held by this semaphore instance
+func (sem *semaphoreDriver) Renew() bool {
+	if rc, ok := sem.Cache.(*redisCache); ok {
+		reply, err := redis.Int(rc.eval(redisSemaphoreRenewScript, []string{sem.Key}, milliseconds(sem.TTL), sem.Token))
+		return err == nil && reply == 1
+	}
+
+	if sem.slot < 0 {
+		return false
+	}
+	if !sem.store.refreshLock(sem.slotKey(sem.slot), sem.Token, sem.TTL) {
+		sem.slot = -1
+		return false
+	}
+	return true
+}
+
+// Count get number of active leases
+func (sem *semaphoreDriver) Count() uint32 {
+	if rc, ok := sem.Cache.(*redisCache); ok {
+		count, _ := redis.Int(rc.eval(redisSemaphoreCountScript, []string{sem.Key}))
+		return uint32(count)
+	}
+
+	// lease locks checked without removing expired ones, taken over lease not lost
+	var count uint32
+	for slot := 0; slot < int(sem.Limit); slot++ {
+		if sem.store.lockHeld(sem.slotKey(slot)) {
+			count++
+		}
+	}
+	return count
+}
+
+// Owner get lease owner token
+func (sem *semaphoreDriver) Owner() string {
+	return sem.Token
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestSemaphores create semaphore instances sharing key
+func newTestSemaphores(t *testing.T, cache Cache, key string, limit uint32, ttl time.Duration, count int) []Semaphore {
+	t.Helper()
+	sems := make([]Semaphore, count)
+	for i := range sems {
+		if sems[i] = NewSemaphore(key, limit, ttl, cache); sems[i] == nil {
+			t.Fatal("semaphore not created")
+		}
+	}
+	return sems
+}
+
+func TestSemaphoreLimit(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			sems := newTestSemaphores(t, cache, "semaphore", 2, time.Minute, 3)
+			defer func() {
+				for _, sem := range sems {
+					sem.Release()
+				}
+			}()
+
+			if !sems[0].TryAcquire() || !sems[1].TryAcquire() {
+				t.Fatal("lease under limit rejected")
+			}
+			if sems[2].TryAcquire() || sems[2].Acquire(10*time.Millisecond) {
+				t.Fatal("lease over limit acquired")
+			}
+			if !sems[0].TryAcquire() || sems[0].Count() != 2 {
+				t.Fatal("held lease not renewed by acquire")
+			}
+
+			if !sems[0].Release() || sems[0].Release() || sems[0].Count() != 1 {
+				t.Fatal("lease not released once")
+			}
+			if sems[0].Renew() {
+				t.Fatal("released lease renewed")
+			}
+			if !sems[2].TryAcquire() || sems[2].Count() != 2 || !sems[1].Renew() {
+				t.Fatal("released lease not reused")
+			}
+		})
+	}
+}
+
+func TestSemaphoreLeaseExpiry(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			sems := newTestSemaphores(t, cache, "semaphore-expiry", 1, 100*time.Millisecond, 2)
+			defer sems[1].Release()
+
+			if !sems[0].TryAcquire() || sems[1].TryAcquire() {
+				t.Fatal("single lease not exclusive")
+			}
+			if !sems[1].Acquire(time.Second) {
+				t.Fatal("expired lease not taken over")
+			}
+			for i := 0; i < 3; i++ {
+				if sems[0].Count() != 1 {
+					t.Fatal("taken over lease not counted")
+				}
+			}
+			if sems[0].Renew() || sems[0].Release() {
+				t.Fatal("expired lease renewed or released")
+			}
+			if !sems[1].Renew() || sems[1].Count() != 1 {
+				t.Fatal("taken over lease lost")
+			}
+
+			time.Sleep(150 * time.Millisecond)
+			if sems[1].Count() != 0 {
+				t.Fatal("expired lease counted")
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package cache
+
+// Message verification code message
+type Message struct {
+	// To recipient address (phone number, email, ...)
+	To string
+	// Body rendered message
+	Body string
+	// Code generated verification code
+	Code string
+}
+
+// Sender interface for verification code delivery channels (sms, email, ...)
+type Sender interface {
+	// Send deliver message to recipient
+	Send(message Message) error
+}
+
+// Dispatcher interface for generating and delivering verification codes
+// code issue rolled back if delivery failed
+type Dispatcher interface {
+	// Dispatch generate code, render template and send it to recipient
+	Dispatch(to string) error
+	// DispatchFor generate code bound to purpose and payload, render template and send it to recipient
+	DispatchFor(to string, purpose string, payload map[string]string) error
+}
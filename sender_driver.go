@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// templateData data passed to dispatcher template
+type templateData struct {
+	Code    string
+	To      string
+	Purpose string
+	Payload map[string]string
+}
+
+// issuer verification code that support rolling back issued code
+type issuer interface {
+	generateFor(purpose string, payload map[string]string) (string, bool, error)
+	rollback(reused bool) error
+}
+
+// dispatcherDriver verification code dispatcher
+type dispatcherDriver struct {
+	Code     VerificationCode
+	Sender   Sender
+	Template *template.Template
+}
+
+func (d *dispatcherDriver) init(code VerificationCode, sender Sender, tpl string) error {
+	parsed, err := template.New("message").Parse(tpl)
+	if err != nil {
+		return err
+	}
+	d.Code = code
+	d.Sender = sender
+	d.Template = parsed
+	return nil
+}
+
+// Dispatch generate code, render template and send it to recipient
+func (d *dispatcherDriver) Dispatch(to string) error {
+	return d.DispatchFor(to, "", nil)
+}
+
+// generate generate code and get rollback function
+// only code cleared on rollback if code manager not support rolling back issue limits
+func (d *dispatcherDriver) generate(purpose string, payload map[string]string) (string, func(), error) {
+	if iss, ok := d.Code.(issuer); ok {
+		code, reused, err := iss.generateFor(purpose, payload)
+		return code, func() { iss.rollback(reused) }, err
+	}
+	code, err := d.Code.GenerateFor(purpose, payload)
+	return code, d.Code.Clear, err
+}
+
+// DispatchFor generate code bound to purpose and payload, render template and send it to recipient
+func (d *dispatcherDriver) DispatchFor(to string, purpose string, payload map[string]string) error {
+	code, rollback, err := d.generate(purpose, payload)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	err = d.Template.Execute(&body, templateData{
+		Code:    code,
+		To:      to,
+		Purpose: purpose,
+		Payload: payload,
+	})
+	if err == nil {
+		err = d.Sender.Send(Message{To: to, Body: body.String(), Code: code})
+	}
+	if err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// MemorySender sender that keep messages in memory for tests
+type MemorySender struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+// Send store message
+func (s *MemorySender) Send(message Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages get sent messages
+func (s *MemorySender) Messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Last get last message sent to recipient
+func (s *MemorySender) Last(to string) (Message, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Clear remove sent messages
+func (s *MemorySender) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = nil
+}
+
+// writerSender sender that write messages to writer for local development
+type writerSender struct {
+	Writer io.Writer
+	mutex  sync.Mutex
+}
+
+// Send write message to writer
+func (s *writerSender) Send(message Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err := fmt.Fprintf(s.Writer, "to: %s\n%s\n\n", message.To, message.Body)
+	return err
+}
+
+// fileSender sender that append messages to file for local development
+type fileSender struct {
+	Path  string
+	mutex sync.Mutex
+}
+
+// Send append message to file
+func (s *fileSender) Send(message Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(f, "to: %s\n%s\n\n", message.To, message.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingSender sender that always fail
+type failingSender struct{}
+
+func (failingSender) Send(message Message) error {
+	return errors.New("delivery failed")
+}
+
+func newTestCode(t *testing.T, options VerificationOptions) VerificationCode {
+	options.Secret = []byte("secret")
+	vc, err := NewVerificationCodeWithOptions("code", time.Minute, NewFileCache("test", t.TempDir()), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vc
+}
+
+func TestDispatcherInvalidTemplate(t *testing.T) {
+	if _, err := NewDispatcher(newTestCode(t, VerificationOptions{}), NewMemorySender(), "{{.Code"); err == nil {
+		t.Fatal("invalid template accepted")
+	}
+}
+
+func TestDispatcherSend(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{})
+	sender := NewMemorySender()
+	dispatcher, err := NewDispatcher(vc, sender, "code {{.Code}} for {{.Payload.email}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.DispatchFor("+100", "confirm", map[string]string{"email": "a@b.c"}); err != nil {
+		t.Fatal(err)
+	}
+	message, ok := sender.Last("+100")
+	if !ok || message.Body != "code "+message.Code+" for a@b.c" {
+		t.Fatalf("message %+v", message)
+	}
+	if res, _ := vc.VerifyFor("confirm", message.Code); !res.Valid() {
+		t.Fatal("sent code not valid")
+	}
+}
+
+func TestDispatcherRollback(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{ResendCooldown: time.Minute, MaxIssues: 1})
+	failing, _ := NewDispatcher(vc, failingSender{}, "{{.Code}}")
+	if err := failing.Dispatch("+100"); err == nil {
+		t.Fatal("delivery error not returned")
+	}
+	if vc.Exists() || vc.CanResendIn() != 0 {
+		t.Fatal("failed delivery not rolled back")
+	}
+
+	sender := NewMemorySender()
+	dispatcher, _ := NewDispatcher(vc, sender, "{{.Code}}")
+	if err := dispatcher.Dispatch("+100"); err != nil {
+		t.Fatalf("retry after failed delivery: %v", err)
+	}
+}
+
+func TestDispatcherRollbackReused(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{ReuseCode: true})
+	sender := NewMemorySender()
+	dispatcher, _ := NewDispatcher(vc, sender, "{{.Code}}")
+	if err := dispatcher.Dispatch("+100"); err != nil {
+		t.Fatal(err)
+	}
+
+	failing, _ := NewDispatcher(vc, failingSender{}, "{{.Code}}")
+	if err := failing.Dispatch("+100"); err == nil {
+		t.Fatal("delivery error not returned")
+	}
+	message, _ := sender.Last("+100")
+	if res, _ := vc.Verify(message.Code); !res.Valid() {
+		t.Fatal("reused code cleared on failed delivery")
+	}
+}
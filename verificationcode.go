@@ -1,17 +1,115 @@
 package cache
 
+import (
+	"errors"
+	"time"
+)
+
+// DefaultVerifyAttempts default maximum failed verify attempts
+const DefaultVerifyAttempts = 5
+
+// NumericCharset digits charset
+const NumericCharset = "0123456789"
+
+// AlphanumericCharset uppercase letters and digits charset
+const AlphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// UnambiguousCharset uppercase letters and digits charset without ambiguous characters (0/O, 1/I/L)
+const UnambiguousCharset = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// unambiguousLetters letters used for @ format placeholder
+const unambiguousLetters = "ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// ErrMissingSecret error returned when verification code, recovery codes or token manager created without secret
+var ErrMissingSecret = errors.New("cache: secret is required")
+
+// ErrCodeNotStored error returned when code could not be stored in cache
+var ErrCodeNotStored = errors.New("cache: verification code not stored")
+
+// ErrResendCooldown error returned when code generated before resend cooldown passed
+var ErrResendCooldown = errors.New("cache: verification code resend cooldown not passed")
+
+// ErrTooManyIssues error returned when maximum issued codes in window reached
+var ErrTooManyIssues = errors.New("cache: too many verification codes issued")
+
+// VerificationOptions verification code options
+type VerificationOptions struct {
+	// MaxAttempts maximum failed verify attempts before code invalidated, DefaultVerifyAttempts used if zero
+	MaxAttempts uint32
+	// Secret server secret key used to hash and encrypt stored codes, required
+	Secret []byte
+	// ResendCooldown minimum time between generating codes
+	ResendCooldown time.Duration
+	// MaxIssues maximum generated codes in issue window, unlimited if zero
+	MaxIssues uint32
+	// IssueWindow issue limit window, code ttl used if zero
+	IssueWindow time.Duration
+	// ReuseCode return existing unexpired code on generate instead of rotating it
+	// code stored encrypted with secret alongside hash
+	ReuseCode bool
+	// Charset characters used to generate codes, NumericCharset used if empty
+	Charset string
+	// Format code format used by Generate (e.g. "@@@@-9999" for "ABCD-1234")
+	// # replaced by charset character, 9 by digit and @ by unambiguous uppercase letter
+	// other characters kept as separator and ignored on verify
+	Format string
+	// CaseInsensitive verify codes case insensitive
+	CaseInsensitive bool
+	// Observer receive generate and verify events, no events sent if nil
+	Observer Observer
+}
+
+// VerifyStatus verify result status
+type VerifyStatus int
+
+const (
+	// VerifyValid code matched and consumed
+	VerifyValid VerifyStatus = iota
+	// VerifyInvalid code not matched
+	VerifyInvalid
+	// VerifyNotFound code not exists or expired
+	VerifyNotFound
+	// VerifyLocked too many failed attempts and code invalidated
+	VerifyLocked
+)
+
+// VerifyResult verify result
+type VerifyResult struct {
+	Status VerifyStatus
+	// AttemptsLeft failed attempts left before code invalidated
+	AttemptsLeft uint32
+	// Payload stored with code on generate, set on valid result only
+	Payload map[string]string
+}
+
+// Valid check if code matched
+func (r VerifyResult) Valid() bool {
+	return r.Status == VerifyValid
+}
+
 // VerificationCode interface for verification code
 type VerificationCode interface {
-	// Set set code
-	Set(value string)
-	// Generate generate a random numeric code with 5 character length
+	// Set set code, code stored as salted keyed hash
+	Set(value string) error
+	// Generate generate a random code with options format or 5 character length if no format set
+	// return ErrResendCooldown or ErrTooManyIssues if issue limits reached
 	Generate() (string, error)
-	// GenerateN generate a random numeric code with special character length
+	// GenerateN generate a random code from options charset with special character length
+	// return ErrResendCooldown or ErrTooManyIssues if issue limits reached
 	GenerateN(count uint) (string, error)
+	// GenerateFor generate a random code like Generate bound to purpose and payload
+	// payload returned on verify so confirmation can trust data stored at issue time
+	GenerateFor(purpose string, payload map[string]string) (string, error)
+	// CanResendIn get time until new code can be generated
+	CanResendIn() time.Duration
 	// Clear clear code
 	Clear()
-	// Get get code
-	Get() string
 	// Exists check if code exists
 	Exists() bool
+	// Verify compare input with code in constant time
+	// code consumed on success and invalidated after too many failed attempts
+	Verify(input string) (VerifyResult, error)
+	// VerifyFor compare input with code issued for purpose in constant time
+	// code issued for other purpose counted as failed attempt
+	VerifyFor(purpose string, input string) (VerifyResult, error)
 }
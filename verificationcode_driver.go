@@ -1,55 +1,435 @@
 package cache
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gobardofw/utils"
 )
 
+// codeMeta verification code purpose and payload
+type codeMeta struct {
+	Purpose string            `json:"purpose"`
+	Payload map[string]string `json:"payload,omitempty"`
+}
+
+// encodeMeta encode code purpose and payload, empty for codes without purpose and payload
+func encodeMeta(purpose string, payload map[string]string) (string, error) {
+	if purpose == "" && len(payload) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(codeMeta{Purpose: purpose, Payload: payload})
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
 // vcDriver verification code manager
 type vcDriver struct {
-	Key   string
-	TTL   time.Duration
-	Cache Cache
+	Key     string
+	TTL     time.Duration
+	Cache   Cache
+	Options VerificationOptions
 }
 
-// Set set code
-func (vc *vcDriver) Set(value string) {
-	vc.Cache.Forget(vc.Key)
-	vc.Cache.Put(vc.Key, value, vc.TTL)
+func (vc *vcDriver) init(key string, ttl time.Duration, cache Cache, options VerificationOptions) error {
+	if len(options.Secret) == 0 {
+		return ErrMissingSecret
+	}
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = DefaultVerifyAttempts
+	}
+	if options.Charset == "" {
+		options.Charset = NumericCharset
+	}
+	vc.Key = key
+	vc.TTL = ttl
+	vc.Cache = cache
+	vc.Options = options
+	return nil
 }
 
-// Generate generate a random numeric code with 5 character length
-func (vc *vcDriver) Generate() (string, error) {
-	val, err := utils.RandomStringFromCharset(5, "0123456789")
+func (vc *vcDriver) attemptsKey() string {
+	return vc.Key + "-attempts"
+}
+
+func (vc *vcDriver) metaKey() string {
+	return vc.Key + "-meta"
+}
+
+func (vc *vcDriver) sealedKey() string {
+	return vc.Key + "-sealed"
+}
+
+func (vc *vcDriver) cooldownKey() string {
+	return vc.Key + "-cooldown"
+}
+
+func (vc *vcDriver) issuesKey() string {
+	return vc.Key + "-issues"
+}
+
+// cipher get authenticated cipher with key derived from secret
+func (vc *vcDriver) cipher() (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, vc.Options.Secret)
+	mac.Write([]byte("verification-code-seal"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypt code
+func (vc *vcDriver) seal(code string) (string, error) {
+	aead, err := vc.cipher()
 	if err != nil {
 		return "", err
 	}
-	vc.Set(val)
-	return val, nil
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(aead.Seal(nonce, nonce, []byte(code), nil)), nil
+}
+
+// open decrypt sealed code
+func (vc *vcDriver) open(sealed string) (string, bool) {
+	aead, err := vc.cipher()
+	if err != nil {
+		return "", false
+	}
+	data, err := hex.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", false
+	}
+	code, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", false
+	}
+	return string(code), true
+}
+
+// isPlaceholder check if format character is placeholder
+func isPlaceholder(r rune) bool {
+	return r == '#' || r == '9' || r == '@'
+}
+
+// format generate random code with format
+func (vc *vcDriver) format() (string, error) {
+	var res strings.Builder
+	for _, r := range vc.Options.Format {
+		charset := ""
+		switch r {
+		case '#':
+			charset = vc.Options.Charset
+		case '9':
+			charset = NumericCharset
+		case '@':
+			charset = unambiguousLetters
+		default:
+			res.WriteRune(r)
+			continue
+		}
+		char, err := utils.RandomStringFromCharset(1, charset)
+		if err != nil {
+			return "", err
+		}
+		res.WriteString(char)
+	}
+	return res.String(), nil
+}
+
+// normalize remove spaces and format separators from code
+// and convert to upper case for case insensitive codes
+// separator removed only at its format position so code characters never stripped
+func (vc *vcDriver) normalize(code string) string {
+	code = strings.Replace(code, " ", "", -1)
+	if vc.Options.CaseInsensitive {
+		code = strings.ToUpper(code)
+	}
+	if vc.Options.Format == "" {
+		return code
+	}
+
+	input := []rune(code)
+	var res strings.Builder
+	for _, r := range vc.Options.Format {
+		if len(input) == 0 {
+			break
+		}
+		if isPlaceholder(r) {
+			res.WriteRune(input[0])
+			input = input[1:]
+			continue
+		}
+		if vc.Options.CaseInsensitive {
+			r = unicode.ToUpper(r)
+		}
+		if input[0] == r {
+			input = input[1:]
+		}
+	}
+	res.WriteString(string(input))
+	return res.String()
+}
+
+// hash get keyed hash of normalized code and encoded meta with salt
+func (vc *vcDriver) hash(code string, salt []byte, meta string) []byte {
+	mac := hmac.New(sha256.New, vc.Options.Secret)
+	mac.Write(salt)
+	mac.Write([]byte(vc.normalize(code)))
+	if meta != "" {
+		mac.Write([]byte{0})
+		mac.Write([]byte(meta))
+	}
+	return mac.Sum(nil)
+}
+
+// match check if code and encoded meta match stored salt and hash in constant time
+func (vc *vcDriver) match(code string, stored string, meta string) bool {
+	parts := strings.SplitN(stored, "$", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(vc.hash(code, salt, meta), hash)
+}
+
+// Set set code, code stored as salted keyed hash
+func (vc *vcDriver) Set(value string) error {
+	return vc.set(value, "")
+}
+
+// set set code bound to encoded meta
+// code cleared if any part not stored
+func (vc *vcDriver) set(value string, meta string) error {
+	vc.Clear()
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	sealed := ""
+	if vc.Options.ReuseCode {
+		var err error
+		if sealed, err = vc.seal(value); err != nil {
+			return err
+		}
+	}
+
+	stored := vc.Cache.Put(vc.Key, hex.EncodeToString(salt)+"$"+hex.EncodeToString(vc.hash(value, salt, meta)), vc.TTL)
+	if stored && meta != "" {
+		stored = vc.Cache.Put(vc.metaKey(), meta, vc.TTL)
+	}
+	if stored && sealed != "" {
+		stored = vc.Cache.Put(vc.sealedKey(), sealed, vc.TTL)
+	}
+	if !stored {
+		vc.Clear()
+		return ErrCodeNotStored
+	}
+	return nil
 }
 
-// GenerateN generate a random numeric code with special character length
+// issue generate code bound to encoded meta if issue limits not reached
+// code generated with format if count is zero, return true if existing code reused
+func (vc *vcDriver) issue(count uint, meta string) (string, bool, error) {
+	if vc.CanResendIn() > 0 {
+		return "", false, ErrResendCooldown
+	}
+	issues := vc.Cache.UInt32(vc.issuesKey(), 0)
+	if vc.Options.MaxIssues > 0 && issues >= vc.Options.MaxIssues {
+		return "", false, ErrTooManyIssues
+	}
+
+	code, reused := "", false
+	if vc.Options.ReuseCode && vc.Exists() && vc.Cache.String(vc.metaKey(), "") == meta {
+		code, reused = vc.open(vc.Cache.String(vc.sealedKey(), ""))
+	}
+	if !reused {
+		var val string
+		var err error
+		if count == 0 {
+			val, err = vc.format()
+		} else {
+			val, err = utils.RandomStringFromCharset(count, vc.Options.Charset)
+		}
+		if err != nil {
+			return "", false, err
+		}
+		code = val
+		if err := vc.set(code, meta); err != nil {
+			return "", false, err
+		}
+	}
+
+	if vc.Options.ResendCooldown > 0 {
+		vc.Cache.Put(vc.cooldownKey(), 1, vc.Options.ResendCooldown)
+	}
+	if vc.Options.MaxIssues > 0 {
+		window := vc.Options.IssueWindow
+		if window <= 0 {
+			window = vc.TTL
+		}
+		incrementWithin(vc.Cache, vc.issuesKey(), window)
+	}
+	return code, reused, nil
+}
+
+// Generate generate a random code with options format or 5 character length if no format set
+func (vc *vcDriver) Generate() (string, error) {
+	return vc.GenerateFor("", nil)
+}
+
+// GenerateN generate a random code from options charset with special character length
 func (vc *vcDriver) GenerateN(count uint) (string, error) {
-	val, err := utils.RandomStringFromCharset(count, "0123456789")
+	if count == 0 {
+		return "", nil
+	}
+	code, _, err := vc.generate(count, "")
+	return code, err
+}
+
+// GenerateFor generate a random code like Generate bound to purpose and payload
+func (vc *vcDriver) GenerateFor(purpose string, payload map[string]string) (string, error) {
+	code, _, err := vc.generateFor(purpose, payload)
+	return code, err
+}
+
+// generateFor generate a random code like GenerateFor and report if existing code reused
+func (vc *vcDriver) generateFor(purpose string, payload map[string]string) (string, bool, error) {
+	meta, err := encodeMeta(purpose, payload)
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	if vc.Options.Format != "" {
+		return vc.generate(0, meta)
+	}
+	return vc.generate(5, meta)
+}
+
+// generate issue code while holding code lock
+func (vc *vcDriver) generate(count uint, meta string) (string, bool, error) {
+	var code string
+	var reused bool
+	var err error
+	if lockErr := synchronize(vc.Cache, vc.Key, func() {
+		code, reused, err = vc.issue(count, meta)
+	}); lockErr != nil {
+		return "", false, lockErr
 	}
-	vc.Set(val)
-	return val, nil
+	if err == nil {
+		notify(vc.Options.Observer, EventCodeGenerated, vc.Key, time.Now(), true, vc.Options.MaxAttempts)
+	}
+	return code, reused, err
+}
+
+// rollback undo code issue after failed delivery
+// resend cooldown and issue count restored, reused code kept since it was delivered before
+func (vc *vcDriver) rollback(reused bool) error {
+	return synchronize(vc.Cache, vc.Key, func() {
+		if !reused {
+			vc.Clear()
+		}
+		vc.Cache.Forget(vc.cooldownKey())
+		if vc.Options.MaxIssues > 0 {
+			decrementExisting(vc.Cache, vc.issuesKey())
+		}
+	})
+}
+
+// CanResendIn get time until new code can be generated
+func (vc *vcDriver) CanResendIn() time.Duration {
+	return vc.Cache.TTL(vc.cooldownKey())
 }
 
 // Clear clear code
 func (vc *vcDriver) Clear() {
 	vc.Cache.Forget(vc.Key)
-}
-
-// Get get code
-func (vc *vcDriver) Get() string {
-	return vc.Cache.String(vc.Key, "")
+	vc.Cache.Forget(vc.attemptsKey())
+	vc.Cache.Forget(vc.metaKey())
+	vc.Cache.Forget(vc.sealedKey())
 }
 
 // Exists check if code exists
 func (vc *vcDriver) Exists() bool {
 	return vc.Cache.Exists(vc.Key)
 }
+
+// Verify compare input with code in constant time
+func (vc *vcDriver) Verify(input string) (VerifyResult, error) {
+	return vc.VerifyFor("", input)
+}
+
+// VerifyFor compare input with code issued for purpose in constant time
+func (vc *vcDriver) VerifyFor(purpose string, input string) (VerifyResult, error) {
+	res := VerifyResult{Status: VerifyNotFound}
+	err := synchronize(vc.Cache, vc.Key, func() {
+		stored := vc.Cache.String(vc.Key, "")
+		if stored == "" {
+			return
+		}
+
+		var meta codeMeta
+		encoded := vc.Cache.String(vc.metaKey(), "")
+		if encoded != "" && json.Unmarshal([]byte(encoded), &meta) != nil {
+			return
+		}
+		if meta.Purpose == purpose && vc.match(input, stored, encoded) {
+			vc.Clear()
+			res = VerifyResult{Status: VerifyValid, Payload: meta.Payload}
+			return
+		}
+
+		attempts := vc.Cache.UInt32(vc.attemptsKey(), 0) + 1
+		if attempts >= vc.Options.MaxAttempts {
+			vc.Clear()
+			res = VerifyResult{Status: VerifyLocked}
+			return
+		}
+		ttl := vc.Cache.TTL(vc.Key)
+		if ttl <= 0 {
+			ttl = vc.TTL
+		}
+		vc.Cache.Put(vc.attemptsKey(), attempts, ttl)
+		res = VerifyResult{
+			Status:       VerifyInvalid,
+			AttemptsLeft: vc.Options.MaxAttempts - attempts,
+		}
+	})
+	if err != nil {
+		return res, err
+	}
+	vc.notify(res)
+	return res, nil
+}
+
+// notify pass verify result event to observers
+func (vc *vcDriver) notify(res VerifyResult) {
+	switch res.Status {
+	case VerifyValid:
+		notify(vc.Options.Observer, EventCodeVerified, vc.Key, time.Now(), true, 0)
+	case VerifyInvalid:
+		notify(vc.Options.Observer, EventCodeFailed, vc.Key, time.Now(), false, res.AttemptsLeft)
+	case VerifyLocked:
+		notify(vc.Options.Observer, EventCodeLocked, vc.Key, time.Now(), false, 0)
+	case VerifyNotFound:
+		notify(vc.Options.Observer, EventCodeExpired, vc.Key, time.Now(), false, 0)
+	}
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerificationCodeRequireSecret(t *testing.T) {
+	cache := NewFileCache("test", t.TempDir())
+	if _, err := NewVerificationCode("code", time.Minute, nil, cache); err != ErrMissingSecret {
+		t.Fatal("code without secret created")
+	}
+	options := VerificationOptions{ReuseCode: true}
+	if _, err := NewVerificationCodeWithOptions("code", time.Minute, cache, options); err != ErrMissingSecret {
+		t.Fatal("reusable code without secret created")
+	}
+	if _, err := NewRecoveryCodes("recovery", []byte{}, cache); err != ErrMissingSecret {
+		t.Fatal("recovery codes without secret created")
+	}
+}
+
+func TestVerificationCodeNotStored(t *testing.T) {
+	vc, err := NewVerificationCode("code", 0, []byte("secret"), NewFileCache("test", t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, err := vc.Generate(); err != ErrCodeNotStored || code != "" {
+		t.Fatalf("unstored code %q returned with %v", code, err)
+	}
+	if err := vc.Set("12345"); err != ErrCodeNotStored || vc.Exists() {
+		t.Fatal("unstored code set")
+	}
+}
+
+func TestVerificationCodeIssueLimit(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("issues", time.Minute, cache, VerificationOptions{
+				Secret:      []byte("secret"),
+				MaxIssues:   2,
+				IssueWindow: 100 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			for i := 0; i < 2; i++ {
+				if _, err := vc.Generate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := vc.Generate(); err != ErrTooManyIssues {
+				t.Fatalf("issue limit not reached: %v", err)
+			}
+			if info := cache.TTLInfo("issues-issues"); !info.HasExpiry {
+				t.Fatal("issue counter without expiration")
+			}
+			time.Sleep(110 * time.Millisecond)
+			if _, err := vc.Generate(); err != nil {
+				t.Fatalf("issue limit not reset: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyForAttempts(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("attempts", time.Minute, cache, VerificationOptions{Secret: []byte("secret"), MaxAttempts: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			code, err := vc.GenerateFor("login", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, left := range []uint32{2, 1} {
+				if res, err := vc.VerifyFor("login", "wrong"); err != nil || res.Status != VerifyInvalid || res.AttemptsLeft != left {
+					t.Fatalf("failed attempt %+v, expected %d attempts left", res, left)
+				}
+			}
+			if res, _ := vc.VerifyFor("login", "wrong"); res.Status != VerifyLocked || vc.Exists() {
+				t.Fatalf("code not locked after max attempts %+v", res)
+			}
+			if res, _ := vc.VerifyFor("login", code); res.Status != VerifyNotFound {
+				t.Fatalf("locked code verified %+v", res)
+			}
+		})
+	}
+}
+
+func TestVerifyForConsume(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("consume", time.Minute, cache, VerificationOptions{Secret: []byte("secret")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			code, err := vc.GenerateFor("login", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			vc.VerifyFor("login", "wrong")
+			if res, err := vc.VerifyFor("login", code); err != nil || !res.Valid() {
+				t.Fatalf("correct code rejected %+v", res)
+			}
+			if res, _ := vc.VerifyFor("login", code); res.Status != VerifyNotFound {
+				t.Fatalf("code consumed twice %+v", res)
+			}
+
+			code, _ = vc.GenerateFor("login", nil)
+			if res, _ := vc.VerifyFor("login", "wrong"); res.AttemptsLeft != DefaultVerifyAttempts-1 {
+				t.Fatalf("attempts not reset for new code %+v", res)
+			}
+			if res, _ := vc.VerifyFor("login", code); !res.Valid() {
+				t.Fatal("new code rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyForExpired(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("expired", 50*time.Millisecond, cache, VerificationOptions{Secret: []byte("secret")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			code, err := vc.GenerateFor("login", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			time.Sleep(60 * time.Millisecond)
+			if res, err := vc.VerifyFor("login", code); err != nil || res.Status != VerifyNotFound {
+				t.Fatalf("expired code verified %+v", res)
+			}
+		})
+	}
+}
+
+func TestVerificationCodeFormat(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{Format: "@@@@-9999"})
+	code, err := vc.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 9 || code[4] != '-' {
+		t.Fatalf("code %q not formatted", code)
+	}
+	for i, r := range code {
+		if i < 4 && !strings.ContainsRune(unambiguousLetters, r) || i > 4 && !strings.ContainsRune(NumericCharset, r) {
+			t.Fatalf("code %q character %q out of format charset", code, r)
+		}
+	}
+
+	for _, input := range []string{code, code[:4] + code[5:], code[:4] + " " + code[5:]} {
+		vc.Set(code)
+		if res, _ := vc.Verify(input); !res.Valid() {
+			t.Fatalf("input %q rejected for %q", input, code)
+		}
+	}
+}
+
+func TestVerificationCodeSeparatorInCode(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{Format: "G-####", Charset: "G12"})
+	if err := vc.Set("G-GG12"); err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{"12", "G-12", "G12", "GG-12"} {
+		if res, _ := vc.Verify(input); res.Valid() {
+			t.Fatalf("input %q accepted for G-GG12", input)
+		}
+	}
+	for _, input := range []string{"G-GG12", "GGG12", "G-G G12"} {
+		vc.Set("G-GG12")
+		if res, _ := vc.Verify(input); !res.Valid() {
+			t.Fatalf("input %q rejected for G-GG12", input)
+		}
+	}
+}
+
+func TestVerificationCodeCharset(t *testing.T) {
+	vc := newTestCode(t, VerificationOptions{Charset: "AB", Format: "###-###"})
+	code, err := vc.GenerateN(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 32 || strings.Trim(code, "AB") != "" {
+		t.Fatalf("code %q out of charset", code)
+	}
+	if res, _ := vc.Verify(code); !res.Valid() {
+		t.Fatal("charset code rejected")
+	}
+	if code, _ = vc.Generate(); len(code) != 7 || strings.Trim(code[:3]+code[4:], "AB") != "" {
+		t.Fatalf("formatted code %q out of charset", code)
+	}
+}
+
+func TestVerificationCodeCase(t *testing.T) {
+	sensitive := newTestCode(t, VerificationOptions{Format: "@@@-999"})
+	sensitive.Set("ABC-123")
+	if res, _ := sensitive.Verify("abc-123"); res.Valid() {
+		t.Fatal("case sensitive code matched lower case input")
+	}
+
+	insensitive := newTestCode(t, VerificationOptions{Format: "@@@x999", CaseInsensitive: true})
+	for _, input := range []string{"abcx123", "ABCX123", "aBc123", "abc 123"} {
+		insensitive.Set("ABCx123")
+		if res, _ := insensitive.Verify(input); !res.Valid() {
+			t.Fatalf("case insensitive input %q rejected", input)
+		}
+	}
+}
+
+func TestVerifyForPurpose(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("purpose", time.Minute, cache, VerificationOptions{Secret: []byte("secret")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			code, err := vc.GenerateFor("change-email", map[string]string{"email": "new@example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, _ := vc.VerifyFor("reset-password", code)
+			if res.Status != VerifyInvalid || res.AttemptsLeft != DefaultVerifyAttempts-1 || res.Payload != nil {
+				t.Fatalf("code accepted for other purpose %+v", res)
+			}
+			if res, _ := vc.Verify(code); res.Status != VerifyInvalid || res.AttemptsLeft != DefaultVerifyAttempts-2 || res.Payload != nil {
+				t.Fatalf("code accepted without purpose %+v", res)
+			}
+			if res, _ := vc.VerifyFor("change-email", "wrong"); res.Status != VerifyInvalid || res.Payload != nil {
+				t.Fatalf("payload returned on failed attempt %+v", res)
+			}
+			res, _ = vc.VerifyFor("change-email", code)
+			if !res.Valid() || res.Payload["email"] != "new@example.com" {
+				t.Fatalf("payload not returned on success %+v", res)
+			}
+		})
+	}
+}
+
+func TestVerifyForTamperedMeta(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			vc, err := NewVerificationCodeWithOptions("tampered", time.Minute, cache, VerificationOptions{Secret: []byte("secret")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vc.Clear()
+			code, err := vc.GenerateFor("change-email", map[string]string{"email": "new@example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tampered, _ := encodeMeta("change-email", map[string]string{"email": "attacker@example.com"})
+			if !cache.Set("tampered-meta", tampered) {
+				t.Fatal("meta not replaced")
+			}
+			if res, _ := vc.VerifyFor("change-email", code); res.Valid() || res.Payload != nil {
+				t.Fatalf("code accepted with tampered payload %+v", res)
+			}
+
+			cache.Forget("tampered-meta")
+			if res, _ := vc.Verify(code); res.Valid() {
+				t.Fatalf("code accepted with removed meta %+v", res)
+			}
+		})
+	}
+}
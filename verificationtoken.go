@@ -0,0 +1,21 @@
+package cache
+
+import "errors"
+
+// ErrTokenNotStored error returned when generated token could not be stored in cache
+var ErrTokenNotStored = errors.New("cache: verification token not stored")
+
+// VerificationToken interface for single use url safe tokens (magic links, password reset)
+type VerificationToken interface {
+	// Generate generate a new single use url safe token
+	Generate() (string, error)
+	// GenerateWith generate a new single use url safe token with payload
+	GenerateWith(payload map[string]string) (string, error)
+	// Redeem consume token and get payload stored on generate
+	// token redeemed only once even on concurrent redeem
+	Redeem(token string) (map[string]string, bool)
+	// Exists check if token not redeemed or expired
+	Exists(token string) bool
+	// Revoke invalidate token
+	Revoke(token string) bool
+}
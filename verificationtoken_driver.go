@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// tokenDriver single use token manager
+// tokens stored as keyed hash, so cache access not reveal valid tokens
+type tokenDriver struct {
+	Key    string
+	TTL    time.Duration
+	Secret []byte
+	Cache  Cache
+}
+
+func (t *tokenDriver) init(key string, ttl time.Duration, secret []byte, cache Cache) error {
+	if len(secret) == 0 {
+		return ErrMissingSecret
+	}
+	t.Key = key
+	t.TTL = ttl
+	t.Secret = secret
+	t.Cache = cache
+	return nil
+}
+
+// tokenKey get cache key of token hash
+func (t *tokenDriver) tokenKey(token string) string {
+	mac := hmac.New(sha256.New, t.Secret)
+	mac.Write([]byte(token))
+	return t.Key + "-" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Generate generate a new single use url safe token
+func (t *tokenDriver) Generate() (string, error) {
+	return t.GenerateWith(nil)
+}
+
+// GenerateWith generate a new single use url safe token with payload
+func (t *tokenDriver) GenerateWith(payload map[string]string) (string, error) {
+	if payload == nil {
+		payload = map[string]string{}
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	if !t.Cache.Put(t.tokenKey(token), string(encoded), t.TTL) {
+		return "", ErrTokenNotStored
+	}
+	return token, nil
+}
+
+// Redeem consume token and get payload stored on generate
+func (t *tokenDriver) Redeem(token string) (map[string]string, bool) {
+	var encoded []byte
+	switch v := t.Cache.Pull(t.tokenKey(token)).(type) {
+	case []byte:
+		encoded = v
+	case string:
+		encoded = []byte(v)
+	default:
+		return nil, false
+	}
+	payload := map[string]string{}
+	if json.Unmarshal(encoded, &payload) != nil {
+		return nil, false
+	}
+	return payload, true
+}
+
+// Exists check if token not redeemed or expired
+func (t *tokenDriver) Exists(token string) bool {
+	return t.Cache.Exists(t.tokenKey(token))
+}
+
+// Revoke invalidate token
+func (t *tokenDriver) Revoke(token string) bool {
+	return t.Cache.Forget(t.tokenKey(token))
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerificationTokenRequireSecret(t *testing.T) {
+	if _, err := NewVerificationToken("token", time.Minute, nil, NewFileCache("test", t.TempDir())); err != ErrMissingSecret {
+		t.Fatal("token manager without secret created")
+	}
+}
+
+func TestVerificationTokenRedeem(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := NewVerificationToken("token", time.Minute, []byte("secret"), cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := tokens.GenerateWith(map[string]string{"user": "42"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tokens.Exists(token) || tokens.Exists(token+"x") {
+				t.Fatal("token existence not checked by token")
+			}
+			if _, ok := tokens.Redeem(token + "x"); ok {
+				t.Fatal("unknown token redeemed")
+			}
+			if payload, ok := tokens.Redeem(token); !ok || payload["user"] != "42" {
+				t.Fatal("token not redeemed with payload")
+			}
+			if _, ok := tokens.Redeem(token); ok || tokens.Exists(token) {
+				t.Fatal("token redeemed twice")
+			}
+
+			token, _ = tokens.Generate()
+			if !tokens.Revoke(token) {
+				t.Fatal("token not revoked")
+			}
+			if _, ok := tokens.Redeem(token); ok {
+				t.Fatal("revoked token redeemed")
+			}
+		})
+	}
+}
+
+func TestVerificationTokenParallelRedeem(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := NewVerificationToken("parallel", time.Minute, []byte("secret"), cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for round := 0; round < 5; round++ {
+				token, err := tokens.Generate()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var wg sync.WaitGroup
+				var mutex sync.Mutex
+				redeemed := 0
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if _, ok := tokens.Redeem(token); ok {
+							mutex.Lock()
+							redeemed++
+							mutex.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+				if redeemed != 1 {
+					t.Fatalf("token redeemed %d times", redeemed)
+				}
+			}
+		})
+	}
+}
+
+func TestVerificationTokenExpiration(t *testing.T) {
+	for name, cache := range testCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := NewVerificationToken("expiring", 50*time.Millisecond, []byte("secret"), cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := tokens.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(60 * time.Millisecond)
+			if _, ok := tokens.Redeem(token); ok || tokens.Exists(token) {
+				t.Fatal("expired token redeemed")
+			}
+		})
+	}
+}